@@ -88,6 +88,11 @@ class Machine {
         return Machine{MachineState::loadFromFile(executable_filename)};
     }
 
+    static Machine loadFromExecutable(LoadedExecutable executable) {
+        return Machine{
+            MachineState::loadFromExecutable(std::move(executable))};
+    }
+
     virtual void abort();
     virtual bool isAborted();
 
@@ -176,6 +176,7 @@ struct MachineState {
     AssertionContext context;
 
     static MachineState loadFromFile(const std::string& executable_filename);
+    static MachineState loadFromExecutable(LoadedExecutable executable);
 
     MachineState(std::shared_ptr<CoreCode> code_, Value static_val);
 
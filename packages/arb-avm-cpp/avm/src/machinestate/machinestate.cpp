@@ -130,7 +130,10 @@ MachineState::MachineState(MachineOutput output_,
 
 MachineState MachineState::loadFromFile(
     const std::string& executable_filename) {
-    auto executable = loadExecutable(executable_filename);
+    return loadFromExecutable(loadExecutable(executable_filename));
+}
+
+MachineState MachineState::loadFromExecutable(LoadedExecutable executable) {
     auto code = std::make_shared<CoreCode>(0);
     code->addSegment(std::move(executable.code));
     return MachineState{std::move(code), std::move(executable.static_val)};
@@ -22,6 +22,8 @@
 
 #include <nlohmann/json.hpp>
 
+#include <istream>
+
 struct LoadedExecutable {
     std::shared_ptr<UnsafeCodeSegment> code;
     Value static_val;
@@ -35,5 +37,6 @@ Value simple_value_from_json(const nlohmann::json& value_json);
 std::vector<uint8_t> send_from_json(const nlohmann::json& val);
 
 LoadedExecutable loadExecutable(const std::string& executable_filename);
+LoadedExecutable loadExecutable(std::istream& executable_input_stream);
 
 #endif /* vmValueParser_hpp */
@@ -33,6 +33,12 @@ constexpr auto OPCODE_SUB_LABEL = "AVMOpcode";
 constexpr auto IMMEDIATE_LABEL = "immediate";
 constexpr auto CODE_LABEL = "code";
 constexpr auto STATIC_LABEL = "static_val";
+constexpr auto VERSION_LABEL = "version";
+constexpr auto EXTENSIONS_LABEL = "extensions";
+
+// Newest executable format this loader understands. Executables from before
+// the format was versioned have no version field and are also accepted.
+constexpr uint64_t MAX_EXECUTABLE_VERSION = 2;
 
 namespace {
 
@@ -118,6 +124,34 @@ Operation operation_from_json(const nlohmann::json& op_json,
     }
     return {opcode, value_from_json(imm, op_count, code)};
 }
+
+void checkExecutableHeader(const nlohmann::json& executable_json) {
+    auto version_it = executable_json.find(VERSION_LABEL);
+    if (version_it != executable_json.end()) {
+        if (!version_it->is_number_unsigned()) {
+            throw std::runtime_error("expected version to be a number");
+        }
+        auto version = version_it->get<uint64_t>();
+        if (version > MAX_EXECUTABLE_VERSION) {
+            throw std::runtime_error(
+                "unsupported executable version " + std::to_string(version) +
+                ", newest supported is " +
+                std::to_string(MAX_EXECUTABLE_VERSION));
+        }
+    }
+    auto extensions_it = executable_json.find(EXTENSIONS_LABEL);
+    if (extensions_it != executable_json.end()) {
+        if (!extensions_it->is_array()) {
+            throw std::runtime_error("expected extensions to be array");
+        }
+        // No extensions are supported yet, so any required one is fatal
+        if (!extensions_it->empty()) {
+            throw std::runtime_error(
+                "executable requires unsupported extension " +
+                extensions_it->front().dump());
+        }
+    }
+}
 }  // namespace
 
 Value simple_value_from_json(const nlohmann::json& full_value_json) {
@@ -170,8 +204,13 @@ LoadedExecutable loadExecutable(const std::string& executable_filename) {
     if (!executable_input_stream.is_open()) {
         throw std::runtime_error("doesn't exist");
     }
+    return loadExecutable(executable_input_stream);
+}
+
+LoadedExecutable loadExecutable(std::istream& executable_input_stream) {
     nlohmann::json executable_json;
     executable_input_stream >> executable_json;
+    checkExecutableHeader(executable_json);
     auto& json_code = executable_json.at(CODE_LABEL);
     if (!json_code.is_array()) {
         throw std::runtime_error("expected code to be array");
@@ -164,6 +164,25 @@ int cleanupValidator(CArbStorage* storage_ptr) {
     }
 }
 
+int arbStorageCreateCheckpoint(CArbStorage* storage_ptr,
+                               const char* checkpoint_dir) {
+    auto storage = static_cast<ArbStorage*>(storage_ptr);
+    try {
+        auto status = storage->createCheckpoint(checkpoint_dir);
+        if (!status.ok()) {
+            std::cerr << "Error creating database checkpoint: "
+                      << status.ToString() << std::endl;
+            return false;
+        }
+
+        return true;
+    } catch (const std::exception& e) {
+        std::cerr << "Exception creating database checkpoint:" << e.what()
+                  << std::endl;
+        return false;
+    }
+}
+
 int arbStorageInitialized(CArbStorage* storage_ptr) {
     return static_cast<ArbStorage*>(storage_ptr)->initialized();
 }
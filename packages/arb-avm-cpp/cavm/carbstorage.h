@@ -74,6 +74,8 @@ int arbStorageInitialized(CArbStorage* storage_ptr);
 void destroyArbStorage(CArbStorage* storage);
 int closeArbStorage(CArbStorage* storage_ptr);
 int cleanupValidator(CArbStorage* storage_ptr);
+int arbStorageCreateCheckpoint(CArbStorage* storage_ptr,
+                               const char* checkpoint_dir);
 
 CArbCore* createArbCore(CArbStorage* storage_ptr);
 CAggregatorStore* createAggregatorStore(CArbStorage* storage_ptr);
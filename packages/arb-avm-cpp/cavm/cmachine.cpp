@@ -28,6 +28,43 @@ typedef struct {
     uint64_t stepCount;
 } cassertion;
 
+namespace {
+std::vector<unsigned char> marshalSends(
+    const std::vector<MachineEmission<std::vector<uint8_t>>>& sends) {
+    std::vector<unsigned char> sendData;
+    for (const auto& send : sends) {
+        auto big_size = boost::endian::native_to_big(
+            static_cast<uint64_t>(send.val.size()));
+        auto big_size_ptr = reinterpret_cast<const char*>(&big_size);
+        sendData.insert(sendData.end(), big_size_ptr,
+                        big_size_ptr + sizeof(big_size));
+        sendData.insert(sendData.end(), send.val.begin(), send.val.end());
+    }
+    return sendData;
+}
+
+std::vector<unsigned char> marshalLogs(
+    const std::vector<MachineEmission<Value>>& logs) {
+    std::vector<unsigned char> logData;
+    for (const auto& log : logs) {
+        marshal_value(log.val, logData, nullptr);
+    }
+    return logData;
+}
+
+// Appends op to a code listing as its immediate count, its opcode and its
+// marshalled immediate if present
+void marshalListingOperation(const Operation& op,
+                             std::vector<unsigned char>& data,
+                             ValueLoader* value_loader) {
+    data.push_back(op.immediate ? 1 : 0);
+    data.push_back(static_cast<unsigned char>(op.opcode));
+    if (op.immediate) {
+        marshal_value(*op.immediate, data, value_loader);
+    }
+}
+}  // namespace
+
 Machine* read_files(const std::string& filename) {
     try {
         return new Machine(Machine::loadFromFile(filename));
@@ -44,6 +81,20 @@ CMachine* machineCreate(const char* filename) {
     return static_cast<void*>(mach);
 }
 
+CMachine* machineCreateFromData(ByteSlice data) {
+    try {
+        std::istringstream stream(
+            std::string(reinterpret_cast<const char*>(data.data),
+                        static_cast<size_t>(data.length)));
+        auto executable = loadExecutable(stream);
+        return static_cast<void*>(
+            new Machine(Machine::loadFromExecutable(std::move(executable))));
+    } catch (const std::exception& e) {
+        std::cerr << "Error loading machine from data: " << e.what() << "\n";
+        return nullptr;
+    }
+}
+
 void machineDestroy(CMachine* m) {
     if (m == nullptr) {
         return;
@@ -91,6 +142,14 @@ void machineCodePointHash(CMachine* m, void* ret) {
     std::copy(val.begin(), val.end(), reinterpret_cast<char*>(ret));
 }
 
+void machineArbGasUsed(CMachine* m, void* ret) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    std::array<unsigned char, 32> val{};
+    to_big_endian(mach->machine_state.output.arb_gas_used, val.begin());
+    std::copy(val.begin(), val.end(), reinterpret_cast<char*>(ret));
+}
+
 CStatus machineCurrentStatus(CMachine* m) {
     auto mach = static_cast<Machine*>(m);
     switch (mach->currentStatus()) {
@@ -138,6 +197,183 @@ CBlockReason machineIsBlocked(CMachine* m, int newMessages) {
     return visit(ReasonConverter{}, blockReason);
 }
 
+CStepResult machineStep(CMachine* m,
+                        int stop_on_breakpoint,
+                        int stop_on_sideload,
+                        int first_instruction) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    try {
+        // Don't inherit these from the last assertion executed on the machine
+        auto& context = mach->machine_state.context;
+        context.stop_on_breakpoint = stop_on_breakpoint != 0;
+        context.stop_on_sideload = stop_on_sideload != 0;
+        context.first_instruction = first_instruction != 0;
+        auto opcode = mach->machine_state.loadCurrentOperation().opcode;
+        auto start_gas = mach->machine_state.output.arb_gas_used;
+        auto blockReason = mach->machine_state.runOne();
+        auto gas_used = mach->machine_state.output.arb_gas_used - start_gas;
+        auto cBlockReason = visit(ReasonConverter{}, blockReason);
+        return {static_cast<uint8_t>(opcode), cBlockReason.blockType,
+                static_cast<uint64_t>(gas_used), true};
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to step machine " << e.what() << "\n";
+        return {0, BLOCK_TYPE_NOT_BLOCKED, 0, false};
+    }
+}
+
+CTracePoint machineTracePoint(CMachine* m, int stack_top_count) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    const auto& state = mach->machine_state;
+    try {
+        auto opcode = state.loadCurrentOperation().opcode;
+        std::vector<unsigned char> stackTop;
+        auto count = std::min(static_cast<uint64_t>(stack_top_count),
+                              state.stack.stacksize());
+        for (uint64_t i = 0; i < count; i++) {
+            marshal_uint256_t(hash_value(state.stack[i]), stackTop);
+        }
+        return {state.pc.segment,
+                state.pc.pc,
+                static_cast<uint8_t>(opcode),
+                state.stack.stacksize(),
+                state.auxstack.stacksize(),
+                returnCharVector(stackTop),
+                true};
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to trace machine " << e.what() << "\n";
+        return {0, 0, 0, 0, 0, ByteSlice{nullptr, 0}, false};
+    }
+}
+
+int machineDeliverMessages(CMachine* m, ByteSliceArray messages) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    try {
+        auto& inbox = mach->machine_state.context.inbox_messages;
+        for (const auto& data : receiveByteSliceArray(messages)) {
+            inbox.push_back(
+                extractMachineMessageImpl(data.begin(), data.end()));
+        }
+        return true;
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to deliver messages " << e.what() << "\n";
+        return false;
+    }
+}
+
+CMachineEmissions machineTakeEmissions(CMachine* m) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    auto& context = mach->machine_state.context;
+    try {
+        auto sendData = marshalSends(context.sends);
+        auto logData = marshalLogs(context.logs);
+        CMachineEmissions emissions{
+            returnCharVector(sendData), static_cast<int>(context.sends.size()),
+            returnCharVector(logData), static_cast<int>(context.logs.size()),
+            true};
+        context.sends.clear();
+        context.logs.clear();
+        return emissions;
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to take machine emissions " << e.what() << "\n";
+        return {ByteSlice{nullptr, 0}, 0, ByteSlice{nullptr, 0}, 0, false};
+    }
+}
+
+ByteSliceResult machineGetValue(CMachine* m, CMachineValue which) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    auto& state = mach->machine_state;
+    try {
+        std::vector<unsigned char> data;
+        switch (which) {
+            case MACHINE_VALUE_REGISTER:
+                marshal_value(state.registerVal, data, &state.value_loader);
+                break;
+            case MACHINE_VALUE_STATIC:
+                marshal_value(state.static_val, data, &state.value_loader);
+                break;
+            case MACHINE_VALUE_IMMEDIATE: {
+                const auto& op = state.loadCurrentOperation();
+                if (!op.immediate) {
+                    return {{}, false};
+                }
+                marshal_value(*op.immediate, data, &state.value_loader);
+                break;
+            }
+            default:
+                return {{}, false};
+        }
+        return {returnCharVector(data), true};
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to get machine value " << e.what() << "\n";
+        return {{}, false};
+    }
+}
+
+ByteSliceResult machineStackValues(CMachine* m, int aux, uint64_t max_count) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    auto& state = mach->machine_state;
+    const auto& stack = aux ? state.auxstack : state.stack;
+    try {
+        std::vector<unsigned char> data;
+        auto count = std::min(max_count, stack.stacksize());
+        for (uint64_t i = 0; i < count; i++) {
+            marshal_value(stack[i], data, &state.value_loader);
+        }
+        return {returnCharVector(data), true};
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to get machine stack " << e.what() << "\n";
+        return {{}, false};
+    }
+}
+
+ByteSliceResult machineCodeListing(CMachine* m, uint64_t max_count) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    auto& state = mach->machine_state;
+    try {
+        auto segment = state.code->loadCodeSegment(state.pc.segment);
+        std::vector<unsigned char> data;
+        auto pc = state.pc;
+        for (uint64_t i = 0; i < max_count; i++) {
+            marshalListingOperation(segment.loadOperation(pc.pc), data,
+                                    &state.value_loader);
+            if (pc.pc == 0) {
+                // The error codepoint ends every segment
+                break;
+            }
+            ++pc;
+        }
+        return {returnCharVector(data), true};
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to list machine code " << e.what() << "\n";
+        return {{}, false};
+    }
+}
+
+ByteSliceResult machineSegmentListing(CMachine* m, uint64_t segment_id) {
+    assert(m);
+    auto mach = static_cast<Machine*>(m);
+    auto& state = mach->machine_state;
+    try {
+        auto segment = state.code->loadCodeSegment(segment_id);
+        std::vector<unsigned char> data;
+        for (uint64_t pc = segment.op_count; pc > 0; pc--) {
+            marshalListingOperation(segment.loadOperation(pc - 1), data,
+                                    &state.value_loader);
+        }
+        return {returnCharVector(data), true};
+    } catch (const std::exception& e) {
+        std::cerr << "Failed to list code segment " << e.what() << "\n";
+        return {{}, false};
+    }
+}
+
 COneStepProof machineMarshallForProof(CMachine* m) {
     assert(m);
     auto mach = static_cast<Machine*>(m);
@@ -230,20 +466,8 @@ RawAssertionResult executeAssertion(CMachine* m,
         if (mach->isAborted()) {
             return {makeEmptyAssertion(), false};
         }
-        std::vector<unsigned char> sendData;
-        for (const auto& send : assertion.sends) {
-            auto big_size = boost::endian::native_to_big(
-                static_cast<uint64_t>(send.val.size()));
-            auto big_size_ptr = reinterpret_cast<const char*>(&big_size);
-            sendData.insert(sendData.end(), big_size_ptr,
-                            big_size_ptr + sizeof(big_size));
-            sendData.insert(sendData.end(), send.val.begin(), send.val.end());
-        }
-
-        std::vector<unsigned char> logData;
-        for (const auto& log : assertion.logs) {
-            marshal_value(log.val, logData, nullptr);
-        }
+        auto sendData = marshalSends(assertion.sends);
+        auto logData = marshalLogs(assertion.logs);
 
         std::vector<unsigned char> debugPrintData;
         int debugPrintDataCount = 0;
@@ -53,7 +53,42 @@ typedef struct {
     ByteSlice buffer_proof;
 } COneStepProof;
 
+typedef struct {
+    uint8_t opcode;
+    enum CBlockType blockType;
+    uint64_t gas_used;
+    int success;
+} CStepResult;
+
+typedef struct {
+    uint64_t segment;
+    uint64_t pc;
+    uint8_t opcode;
+    uint64_t stack_size;
+    uint64_t auxstack_size;
+    // 32 byte hashes of the top stack values, top first
+    ByteSlice stack_top;
+    int success;
+} CTracePoint;
+
+typedef enum {
+    MACHINE_VALUE_REGISTER = 0,
+    MACHINE_VALUE_STATIC = 1,
+    // The immediate of the current operation, which may not exist
+    MACHINE_VALUE_IMMEDIATE = 2
+} CMachineValue;
+
+typedef struct {
+    ByteSlice sends;
+    int send_count;
+    ByteSlice logs;
+    int log_count;
+    int success;
+} CMachineEmissions;
+
 CMachine* machineCreate(const char* filename);
+// Creates a machine from the contents of an executable file
+CMachine* machineCreateFromData(ByteSlice data);
 void machineDestroy(CMachine* m);
 void machineAbort(CMachine* m);
 
@@ -68,6 +103,31 @@ CBlockReason machineIsBlocked(CMachine* m, int newMessages);
 RawAssertionResult executeAssertion(CMachine* m,
                                     const CMachineExecutionConfig* c);
 
+// Executes a single instruction. The machine blocks on breakpoint and
+// sideload instructions only if the corresponding flag is set and
+// first_instruction is unset.
+CStepResult machineStep(CMachine* m,
+                        int stop_on_breakpoint,
+                        int stop_on_sideload,
+                        int first_instruction);
+CTracePoint machineTracePoint(CMachine* m, int stack_top_count);
+int machineDeliverMessages(CMachine* m, ByteSliceArray messages);
+CMachineEmissions machineTakeEmissions(CMachine* m);
+
+// Returns the marshalled value, with found unset if it doesn't exist or
+// couldn't be marshalled
+ByteSliceResult machineGetValue(CMachine* m, CMachineValue which);
+// Returns up to max_count marshalled values from the top of the data stack, or
+// the aux stack if aux is set, top first
+ByteSliceResult machineStackValues(CMachine* m, int aux, uint64_t max_count);
+// Returns up to max_count operations of the current code segment starting at
+// the current pc and following execution order. Each is encoded as an
+// immediate count, the opcode and the marshalled immediate if present.
+ByteSliceResult machineCodeListing(CMachine* m, uint64_t max_count);
+// Returns every operation of the given code segment, from the highest pc
+// down to the error codepoint at pc 0, encoded as in machineCodeListing
+ByteSliceResult machineSegmentListing(CMachine* m, uint64_t segment_id);
+
 COneStepProof machineMarshallForProof(CMachine* m);
 
 ByteSlice machineMarshallState(CMachine* m);
@@ -76,6 +136,10 @@ char* machineInfo(CMachine* m);
 
 void machineCodePointHash(CMachine* m, void*);
 
+// Ret must have 32 bytes of storage allocated for the returned big endian
+// total ArbGas used
+void machineArbGasUsed(CMachine* m, void* ret);
+
 CMachineExecutionConfig* machineExecutionConfigCreate();
 void machineExecutionConfigDestroy(CMachineExecutionConfig* m);
 void* machineExecutionConfigClone(CMachineExecutionConfig* c);
@@ -456,7 +456,7 @@ func (ac *ArbCore) GetLastMachine() (machine.Machine, error) {
 	if cMachine == nil {
 		return nil, errors.Errorf("error getting last machine")
 	}
-	ret := &Machine{cMachine}
+	ret := &Machine{c: cMachine}
 
 	runtime.SetFinalizer(ret, cdestroyVM)
 	return ret, nil
@@ -499,7 +499,7 @@ func (ac *ArbCore) TakeMachine(executionCursor core.ExecutionCursor) (machine.Ma
 	if cMachine == nil {
 		return nil, errors.Errorf("error taking machine from execution cursor")
 	}
-	ret := &Machine{cMachine}
+	ret := &Machine{c: cMachine}
 
 	runtime.SetFinalizer(ret, cdestroyVM)
 	return ret, nil
@@ -17,12 +17,21 @@
 package cmachine
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
 )
 
 func TestMachineCreation(t *testing.T) {
@@ -69,3 +78,759 @@ func TestMachineCreation(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMachineStep(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		hashBefore := mach.Hash()
+		_, blockReason, err := mach.Step()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blockReason != nil {
+			if mach.Hash() != hashBefore {
+				t.Error("blocked step modified machine")
+			}
+			return
+		}
+		if mach.Hash() == hashBefore {
+			t.Error("step didn't modify machine")
+		}
+	}
+}
+
+func TestRunWithGasLimit(t *testing.T) {
+	// Jumps to itself forever
+	loop := []value.Operation{
+		value.ImmediateOperation{Op: 0x34, Val: value.CodePointStub{PC: 1}},
+	}
+	mach, err := NewFromCode(loop, value.NewEmptyTuple())
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe, err := mach.Clone().(*Machine).Run(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probe.Gas == 0 {
+		t.Fatal("loop instruction used no gas")
+	}
+
+	// The limit is a multiple of the instruction cost so the loop can use
+	// all of it
+	maxGas := probe.Gas * 10
+	steps, gasUsed, limitHit, err := mach.RunWithGasLimit(context.Background(), maxGas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limitHit {
+		t.Fatal("expected loop to hit gas limit")
+	}
+	if gasUsed != maxGas {
+		t.Errorf("used %v gas with limit %v", gasUsed, maxGas)
+	}
+	if steps != 10 {
+		t.Errorf("expected 10 steps but got %v", steps)
+	}
+	if mach.IsBlocked(false) != nil {
+		t.Error("reported gas limit hit on blocked machine")
+	}
+	if mach.ArbGasUsed().Uint64() != maxGas {
+		t.Errorf("machine reports %v total gas used after using %v", mach.ArbGasUsed(), maxGas)
+	}
+
+	// The limit applies to each run, not to the machine's total gas used
+	steps, gasUsed, limitHit, err = mach.RunWithGasLimit(context.Background(), maxGas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limitHit || gasUsed != maxGas || steps != 10 {
+		t.Errorf("second run took %v steps using %v gas with limit %v", steps, gasUsed, maxGas)
+	}
+
+	halt := []value.Operation{value.BasicOperation{Op: 0x74}}
+	mach, err = NewFromCode(halt, value.NewEmptyTuple())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, gasUsed, limitHit, err = mach.RunWithGasLimit(context.Background(), maxGas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Error("reported gas limit hit on halted machine")
+	}
+	if gasUsed >= maxGas {
+		t.Errorf("halting used %v gas with limit %v", gasUsed, maxGas)
+	}
+}
+
+func TestTraceHandler(t *testing.T) {
+	// Blocks reading the empty inbox after two instructions
+	code := []value.Operation{
+		value.BasicOperation{Op: 0x3b},
+		value.BasicOperation{Op: 0x3b},
+		value.BasicOperation{Op: 0x72},
+	}
+	mach, err := NewFromCode(code, value.NewEmptyTuple())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceBuffer := machine.NewTraceBuffer(1)
+	var pcs []uint64
+	mach.SetTraceHandler(func(entry machine.TraceEntry) {
+		pcs = append(pcs, entry.PC)
+		traceBuffer.Add(entry)
+	})
+	for i := 0; i < 20; i++ {
+		_, blockReason, err := mach.Step()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blockReason != nil {
+			break
+		}
+	}
+
+	if len(pcs) != 2 || pcs[0] != 3 || pcs[1] != 2 {
+		t.Fatal("expected only executed instructions to be traced but got pcs", pcs)
+	}
+	entries := traceBuffer.Entries()
+	if len(entries) != 1 || entries[0].PC != 2 {
+		t.Error("trace buffer didn't keep the last entry", entries)
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	code := []value.Operation{
+		value.BasicOperation{Op: 0x3b},
+		value.BasicOperation{Op: 0x3b},
+		value.BasicOperation{Op: 0x74},
+	}
+	mach, err := NewFromCode(code, value.NewEmptyTuple())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probe := mach.Clone().(*Machine)
+	if _, blockReason, err := probe.Step(); err != nil {
+		t.Fatal(err)
+	} else if blockReason != nil {
+		t.Fatal("probe blocked", blockReason)
+	}
+	target := probe.CodePointHash()
+
+	// A breakpoint on the starting codepoint doesn't stop the run
+	mach.AddBreakpoint(mach.CodePointHash())
+	mach.AddBreakpoint(target)
+	result, err := mach.Run(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := machine.CodePointBreakpointBlocked{CodePointHash: target}
+	if result.Reason != machine.StopBreakpoint || !expected.Equals(result.BlockReason) {
+		t.Fatalf("expected breakpoint but got %v", result)
+	}
+	if result.Steps != 1 {
+		t.Errorf("expected breakpoint after 1 step but got %v", result.Steps)
+	}
+	if mach.Hash() != probe.Hash() {
+		t.Error("machine stopped at wrong state")
+	}
+
+	result, err = mach.Run(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reason != machine.StopHalt || result.Steps != 2 {
+		t.Error("expected resumed run to halt but got", result)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalHash := mach.Hash()
+
+	clone := mach.Clone().(*Machine)
+	if clone.Hash() != originalHash {
+		t.Fatal("clone has different hash")
+	}
+	if _, err := clone.Run(100); err != nil {
+		t.Fatal(err)
+	}
+	if mach.Hash() != originalHash {
+		t.Error("executing clone modified original machine")
+	}
+}
+
+func TestRunCtxCancelled(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	initialHash := mach.Hash()
+	result, err := mach.RunCtx(ctx, 1000)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled but got %v", err)
+	}
+	if result.Steps != 0 {
+		t.Errorf("expected no steps but got %v", result.Steps)
+	}
+	if mach.Hash() != initialHash {
+		t.Error("cancelled run changed machine state")
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	logVal := value.NewInt64Value(7)
+	tests := []struct {
+		name   string
+		code   []value.Operation
+		reason machine.StopReason
+		steps  uint64
+	}{
+		{
+			"halt",
+			[]value.Operation{
+				value.ImmediateOperation{Op: 0x61, Val: logVal},
+				value.BasicOperation{Op: 0x74},
+			},
+			machine.StopHalt,
+			2,
+		},
+		{
+			"error",
+			[]value.Operation{
+				value.ImmediateOperation{Op: 0x61, Val: logVal},
+				value.BasicOperation{Op: 0x73},
+			},
+			machine.StopError,
+			2,
+		},
+		{
+			"inbox",
+			[]value.Operation{
+				value.ImmediateOperation{Op: 0x61, Val: logVal},
+				value.BasicOperation{Op: 0x72},
+			},
+			machine.StopInbox,
+			1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mach, err := NewFromCode(test.code, value.NewEmptyTuple())
+			if err != nil {
+				t.Fatal(err)
+			}
+			reference := mach.Clone()
+
+			result, err := mach.Run(1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Reason != test.reason || result.Steps != test.steps {
+				t.Fatalf("expected %v after %v steps but got %v", test.reason, test.steps, result)
+			}
+			if result.Reason != machine.StopReasonFromBlockReason(mach.IsBlocked(false)) {
+				t.Errorf("run stopped with %v but machine is blocked with %v", result.Reason, mach.IsBlocked(false))
+			}
+			if test.reason == machine.StopError {
+				if result.ErrorOpcode == nil || *result.ErrorOpcode != 0x73 {
+					t.Error("wrong error opcode", result)
+				}
+			} else if result.ErrorOpcode != nil {
+				t.Error("unexpected error opcode", result)
+			}
+			if len(result.Logs) != 1 || !value.Eq(result.Logs[0], logVal) {
+				t.Error("wrong logs", result.Logs)
+			}
+
+			assertion, _, steps, err := reference.ExecuteAssertion(context.Background(), 0, false, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Steps != steps {
+				t.Errorf("run executed %v steps but assertion executed %v", result.Steps, steps)
+			}
+			if result.Gas != assertion.NumGas {
+				t.Errorf("run used %v gas but assertion used %v", result.Gas, assertion.NumGas)
+			}
+			if len(result.Sends) != len(assertion.Sends) || len(result.Logs) != len(assertion.Logs) {
+				t.Errorf(
+					"run emitted %v sends and %v logs but assertion emitted %v and %v",
+					len(result.Sends),
+					len(result.Logs),
+					len(assertion.Sends),
+					len(assertion.Logs),
+				)
+			}
+		})
+	}
+}
+
+func TestDeliverMessages(t *testing.T) {
+	code := []value.Operation{
+		value.BasicOperation{Op: 0x72},
+		value.BasicOperation{Op: 0x74},
+	}
+	mach, err := NewFromCode(code, value.NewEmptyTuple())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := mach.Run(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reason != machine.StopInbox || result.Steps != 0 {
+		t.Fatal("expected machine to block on the inbox but got", result)
+	}
+
+	if err := mach.DeliverMessages([]inbox.InboxMessage{inbox.NewRandomInboxMessage()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, blockReason, err := mach.Step(); err != nil {
+		t.Fatal(err)
+	} else if blockReason != nil {
+		t.Fatalf("machine still blocked after delivering message: %v", blockReason)
+	}
+	result, err = mach.Run(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reason != machine.StopHalt {
+		t.Error("expected machine to halt after reading message but got", result)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiler := machine.NewProfiler()
+	mach.SetProfiler(profiler)
+	result, err := mach.Run(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := profiler.Report()
+	if report.Total.Count != result.Steps || report.Total.Gas != result.Gas {
+		t.Errorf("profile recorded %v but run executed %v steps using %v gas", report.Total, result.Steps, result.Gas)
+	}
+	var opcodeTotal, codePointTotal uint64
+	for _, entry := range report.Opcodes {
+		opcodeTotal += entry.Count
+	}
+	for _, entry := range report.CodePoints {
+		codePointTotal += entry.Count
+	}
+	if opcodeTotal != result.Steps || codePointTotal != result.Steps {
+		t.Errorf("profile breakdowns count %v and %v instructions but run executed %v", opcodeTotal, codePointTotal, result.Steps)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mach.Run(100); err != nil {
+		t.Fatal(err)
+	}
+
+	inspection, err := mach.Inspect(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStack := inspection.StackSize
+	if expectedStack > 2 {
+		expectedStack = 2
+	}
+	if uint64(len(inspection.Stack)) != expectedStack {
+		t.Errorf("expected %v stack values but got %v", expectedStack, len(inspection.Stack))
+	}
+	point, err := mach.TracePoint(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspection.Operation.GetOp() != point.Opcode {
+		t.Errorf("inspected opcode 0x%x but trace has 0x%x", inspection.Operation.GetOp(), point.Opcode)
+	}
+	t.Log(inspection.Format(80))
+}
+
+func TestRunUntilBlocked(t *testing.T) {
+	// Passes over a breakpoint instruction and a codepoint breakpoint
+	// before blocking on the inbox
+	code := []value.Operation{
+		value.BasicOperation{Op: 0x3b},
+		value.BasicOperation{Op: 0x60},
+		value.BasicOperation{Op: 0x3b},
+		value.BasicOperation{Op: 0x72},
+	}
+	mach, err := NewFromCode(code, value.NewEmptyTuple())
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe := mach.Clone().(*Machine)
+	if _, _, err := probe.Step(); err != nil {
+		t.Fatal(err)
+	}
+	mach.AddBreakpoint(probe.CodePointHash())
+
+	result, err := mach.RunUntilBlocked(context.Background(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reason != machine.StopInbox || result.Steps != 3 {
+		t.Fatal("expected machine to block on the inbox after 3 steps but got", result)
+	}
+	if !result.BlockReason.Equals(mach.IsBlocked(false)) {
+		t.Errorf("run stopped with %v but machine is blocked with %v", result.BlockReason, mach.IsBlocked(false))
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := mach.Disassemble(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("expected code listing")
+	}
+	op, err := mach.CurrentOperation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entries[0].Operation.Equals(op) {
+		t.Errorf("listing starts with %v but current operation is %v", entries[0].Operation, op)
+	}
+	for _, entry := range entries {
+		t.Log(entry)
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	mach1, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	mach2, err := NewFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mach1.Hash() != mach2.Hash() {
+		t.Error("machine loaded from reader has different hash")
+	}
+
+	if _, err := NewFromBytes([]byte("not an executable")); err == nil {
+		t.Error("expected error loading invalid executable")
+	}
+}
+
+func TestNewChecked(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewChecked(codeFile, mach.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewChecked(codeFile, common.Hash{}); err == nil {
+		t.Error("expected error loading machine with wrong hash")
+	}
+}
+
+func TestExecutableHeader(t *testing.T) {
+	data, err := ioutil.ReadFile(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var executable map[string]json.RawMessage
+	if err := json.Unmarshal(data, &executable); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(version string, extensions string) error {
+		executable["version"] = json.RawMessage(version)
+		executable["extensions"] = json.RawMessage(extensions)
+		modified, err := json.Marshal(executable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewFromBytes(modified)
+		return err
+	}
+
+	if err := load("2", "[]"); err != nil {
+		t.Fatal(err)
+	}
+	if err := load("3", "[]"); err == nil {
+		t.Error("expected error loading newer executable version")
+	}
+	if err := load("2", `["unknown"]`); err == nil {
+		t.Error("expected error loading executable with unsupported extension")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := mach.Analyze(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.InvalidOpcodes) != 0 {
+		t.Error("compiled code has invalid opcodes", analysis.InvalidOpcodes)
+	}
+	if len(analysis.InvalidJumps) != 0 {
+		t.Error("compiled code has invalid jumps", analysis.InvalidJumps)
+	}
+	if len(analysis.Blocks) == 0 {
+		t.Error("no blocks found in compiled code")
+	}
+}
+
+func TestWriteExecutable(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := mach.WriteExecutable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewFromReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mach.Hash() != reloaded.Hash() {
+		t.Error("reloaded machine has different hash")
+	}
+}
+
+func TestWriteExecutableAfterRunning(t *testing.T) {
+	// Loops through a nop and a jump back to it
+	code := []value.Operation{
+		value.BasicOperation{Op: 0x3b},
+		value.ImmediateOperation{Op: 0x34, Val: value.CodePointStub{PC: 2}},
+	}
+	static := value.NewTuple2(value.NewInt64Value(1), value.NewInt64Value(2))
+	mach, err := NewFromCode(code, static)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialHash := mach.Hash()
+	for i := 0; i < 3; i++ {
+		if _, _, err := mach.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mach.Hash() == initialHash {
+		t.Fatal("stepping didn't change the machine")
+	}
+
+	var buf bytes.Buffer
+	if err := mach.WriteExecutable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewFromReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Hash() != initialHash {
+		t.Error("reloaded machine doesn't match the original program")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := mach.Clone().(*Machine)
+	diffs, err := mach.Compare(clone, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatal("clone differs from original", diffs)
+	}
+
+	if _, err := clone.Run(1); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = mach.Compare(clone, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) == 0 || diffs[0].Component != "codepoint" {
+		t.Error("expected codepoint to differ after step", diffs)
+	}
+}
+
+func TestNewFromCode(t *testing.T) {
+	// Jumps over an error to halt
+	code := []value.Operation{
+		value.ImmediateOperation{Op: 0x34, Val: value.CodePointStub{PC: 1}},
+		value.BasicOperation{Op: 0x73},
+		value.BasicOperation{Op: 0x74},
+	}
+	mach, err := NewFromCode(code, value.NewEmptyTuple())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := mach.Run(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reason != machine.StopHalt {
+		t.Error("wrong stop reason", result.Reason)
+	}
+}
+
+func TestRunStopsOnBlockingOps(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          value.Operation
+		reason      machine.StopReason
+		blockReason machine.BlockReason
+	}{
+		{"breakpoint", value.BasicOperation{Op: 0x60}, machine.StopBreakpoint, machine.BreakpointBlocked{}},
+		{"sideload", value.ImmediateOperation{Op: 0x7b, Val: value.NewInt64Value(5)}, machine.StopSideload, machine.SideloadBlocked{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code := []value.Operation{
+				value.BasicOperation{Op: 0x3b},
+				test.op,
+				value.BasicOperation{Op: 0x74},
+			}
+			mach, err := NewFromCode(code, value.NewEmptyTuple())
+			if err != nil {
+				t.Fatal(err)
+			}
+			profiler := machine.NewProfiler()
+			mach.SetProfiler(profiler)
+			result, err := mach.Run(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Reason != test.reason || !test.blockReason.Equals(result.BlockReason) {
+				t.Fatalf("expected %v but got %v", test.reason, result)
+			}
+			if result.Steps != 1 {
+				t.Errorf("expected to stop after 1 step but got %v", result.Steps)
+			}
+			if profiler.Report().Total.Count != 1 {
+				t.Error("profiler recorded blocked step")
+			}
+
+			// The blocking instruction doesn't stop a resumed run
+			result, err = mach.Run(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Reason != machine.StopHalt || result.Steps != 2 {
+				t.Error("expected resumed run to halt but got", result)
+			}
+		})
+	}
+}
+
+func TestSymbolizeProfile(t *testing.T) {
+	// Loops through a nop and a jump back to it
+	code := []value.Operation{
+		value.BasicOperation{Op: 0x3b},
+		value.ImmediateOperation{Op: 0x34, Val: value.CodePointStub{PC: 2}},
+	}
+	var buf bytes.Buffer
+	if err := machine.WriteExecutable(&buf, codeListing(code), value.NewEmptyTuple()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add debug info giving each operation its index as its line
+	var executable map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &executable); err != nil {
+		t.Fatal(err)
+	}
+	var ops []map[string]interface{}
+	if err := json.Unmarshal(executable["code"], &ops); err != nil {
+		t.Fatal(err)
+	}
+	for i, op := range ops {
+		op["debug_info"] = map[string]interface{}{
+			"location": map[string]interface{}{"line": i, "column": 1, "file_id": 1},
+			"function": "main",
+		}
+	}
+	codeData, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executable["code"] = codeData
+	executable["file_name_chart"] = json.RawMessage(`{"1": "main.mini"}`)
+	data, err := json.Marshal(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mach, err := NewFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbols, err := machine.LoadSymbolTable(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiler := machine.NewProfiler()
+	mach.SetProfiler(profiler)
+	if _, err := mach.Run(100); err != nil {
+		t.Fatal(err)
+	}
+
+	report := profiler.Report()
+	if len(report.CodePoints) != 2 {
+		t.Fatal("expected 2 profiled codepoints but got", report.CodePoints)
+	}
+	for _, entry := range report.CodePoints {
+		loc, ok := symbols.Lookup(entry.Segment, entry.PC)
+		if !ok {
+			t.Fatalf("no symbol for profiled codepoint %v:%v", entry.Segment, entry.PC)
+		}
+		expectedLine := uint64(len(code)) - entry.PC
+		if loc.File != "main.mini" || loc.Line != expectedLine || loc.Function != "main" {
+			t.Errorf("codepoint %v symbolized as %v", entry.PC, loc)
+		}
+	}
+	if formatted := report.FormatWithSymbols(10, symbols); !strings.Contains(formatted, "at main (main.mini:") {
+		t.Error("formatted report missing source locations", formatted)
+	}
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmachine
+
+/*
+#include "../cavm/cmachine.h"
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func (m *Machine) getValue(which C.CMachineValue) (value.Value, bool, error) {
+	defer runtime.KeepAlive(m)
+	result := C.machineGetValue(m.c, which)
+	if result.found == 0 {
+		return nil, false, nil
+	}
+	val, err := value.UnmarshalValue(bytes.NewReader(receiveByteSlice(result.slice)))
+	return val, true, err
+}
+
+// Register returns the value in the machine's register
+func (m *Machine) Register() (value.Value, error) {
+	val, found, err := m.getValue(C.MACHINE_VALUE_REGISTER)
+	if err == nil && !found {
+		err = errors.New("failed to load register")
+	}
+	return val, err
+}
+
+// StaticValue returns the machine's static value
+func (m *Machine) StaticValue() (value.Value, error) {
+	val, found, err := m.getValue(C.MACHINE_VALUE_STATIC)
+	if err == nil && !found {
+		err = errors.New("failed to load static value")
+	}
+	return val, err
+}
+
+// CurrentOperation returns the operation the machine will execute next
+func (m *Machine) CurrentOperation() (value.Operation, error) {
+	point, err := m.TracePoint(0)
+	if err != nil {
+		return nil, err
+	}
+	immediate, found, err := m.getValue(C.MACHINE_VALUE_IMMEDIATE)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return value.BasicOperation{Op: point.Opcode}, nil
+	}
+	return value.ImmediateOperation{Op: point.Opcode, Val: immediate}, nil
+}
+
+func (m *Machine) stackValues(aux bool, maxCount uint64) ([]value.Value, error) {
+	defer runtime.KeepAlive(m)
+	result := C.machineStackValues(m.c, boolToCInt(aux), C.uint64_t(maxCount))
+	if result.found == 0 {
+		return nil, errors.New("failed to load stack")
+	}
+	rd := bytes.NewReader(receiveByteSlice(result.slice))
+	var vals []value.Value
+	for {
+		val, err := value.UnmarshalValue(rd)
+		if err == io.EOF {
+			return vals, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+}
+
+// Stack returns up to maxCount values from the top of the data stack, top
+// first
+func (m *Machine) Stack(maxCount uint64) ([]value.Value, error) {
+	return m.stackValues(false, maxCount)
+}
+
+// AuxStack returns up to maxCount values from the top of the aux stack, top
+// first
+func (m *Machine) AuxStack(maxCount uint64) ([]value.Value, error) {
+	return m.stackValues(true, maxCount)
+}
+
+// Inspect captures the machine's current state including up to
+// maxStackValues values from the top of each stack
+func (m *Machine) Inspect(maxStackValues uint64) (*machine.Inspection, error) {
+	point, err := m.TracePoint(0)
+	if err != nil {
+		return nil, err
+	}
+	op, err := m.CurrentOperation()
+	if err != nil {
+		return nil, err
+	}
+	register, err := m.Register()
+	if err != nil {
+		return nil, err
+	}
+	static, err := m.StaticValue()
+	if err != nil {
+		return nil, err
+	}
+	stack, err := m.Stack(maxStackValues)
+	if err != nil {
+		return nil, err
+	}
+	auxStack, err := m.AuxStack(maxStackValues)
+	if err != nil {
+		return nil, err
+	}
+	return &machine.Inspection{
+		CodePointHash: m.CodePointHash(),
+		Segment:       point.Segment,
+		PC:            point.PC,
+		Operation:     op,
+		Register:      register,
+		Static:        static,
+		StackSize:     point.StackSize,
+		AuxStackSize:  point.AuxStackSize,
+		Stack:         stack,
+		AuxStack:      auxStack,
+	}, nil
+}
+
+// Disassemble lists up to maxCount operations of the machine's current code
+// segment in execution order, starting with the next operation to execute.
+// The listing stops early at the error codepoint that ends the segment.
+func (m *Machine) Disassemble(maxCount uint64) ([]machine.CodeListingEntry, error) {
+	defer runtime.KeepAlive(m)
+	point, err := m.TracePoint(0)
+	if err != nil {
+		return nil, err
+	}
+	result := C.machineCodeListing(m.c, C.uint64_t(maxCount))
+	if result.found == 0 {
+		return nil, errors.New("failed to list machine code")
+	}
+	rd := bytes.NewReader(receiveByteSlice(result.slice))
+	var entries []machine.CodeListingEntry
+	for pc := point.PC; rd.Len() > 0; pc-- {
+		op, err := value.NewOperationFromReader(rd)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, machine.CodeListingEntry{
+			Segment:   point.Segment,
+			PC:        pc,
+			Operation: op,
+		})
+	}
+	return entries, nil
+}
+
+// Analyze statically examines up to maxCount operations of the current code
+// segment, starting from the current pc
+func (m *Machine) Analyze(maxCount uint64) (*machine.CodeAnalysis, error) {
+	listing, err := m.Disassemble(maxCount)
+	if err != nil {
+		return nil, err
+	}
+	static, err := m.StaticValue()
+	if err != nil {
+		return nil, err
+	}
+	return machine.AnalyzeCode(listing, static), nil
+}
+
+// DisassembleSegment lists every operation of the given code segment in
+// execution order, from its highest pc down to the error codepoint at pc 0
+func (m *Machine) DisassembleSegment(segment uint64) ([]machine.CodeListingEntry, error) {
+	defer runtime.KeepAlive(m)
+	result := C.machineSegmentListing(m.c, C.uint64_t(segment))
+	if result.found == 0 {
+		return nil, errors.Errorf("failed to list code segment %v", segment)
+	}
+	rd := bytes.NewReader(receiveByteSlice(result.slice))
+	var ops []value.Operation
+	for rd.Len() > 0 {
+		op, err := value.NewOperationFromReader(rd)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	entries := make([]machine.CodeListingEntry, 0, len(ops))
+	for i, op := range ops {
+		entries = append(entries, machine.CodeListingEntry{
+			Segment:   segment,
+			PC:        uint64(len(ops) - 1 - i),
+			Operation: op,
+		})
+	}
+	return entries, nil
+}
+
+// WriteExecutable writes the machine's program, its whole initial code
+// segment, and its static value in the executable format. Loading the result
+// gives a machine in the initial state of this one, without debug info, no
+// matter how far this machine has run. Code that pushinsn appended to the
+// initial segment is included.
+func (m *Machine) WriteExecutable(w io.Writer) error {
+	listing, err := m.DisassembleSegment(0)
+	if err != nil {
+		return err
+	}
+	static, err := m.StaticValue()
+	if err != nil {
+		return err
+	}
+	return machine.WriteExecutable(w, listing, static)
+}
+
+// Compare inspects both machines, capturing up to maxStackValues from each
+// stack, and returns the components of their state that differ
+func (m *Machine) Compare(other *Machine, maxStackValues uint64) ([]machine.StateDifference, error) {
+	a, err := m.Inspect(maxStackValues)
+	if err != nil {
+		return nil, err
+	}
+	b, err := other.Inspect(maxStackValues)
+	if err != nil {
+		return nil, err
+	}
+	return machine.CompareInspections(a, b), nil
+}
@@ -25,7 +25,12 @@ package cmachine
 import "C"
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
 	"runtime"
 	"unsafe"
 
@@ -44,8 +49,18 @@ var (
 	StepsCounter = metrics.NewRegisteredCounter("arbitrum/nonmutating/steps_used", nil)
 )
 
+// Number of stack values summarized in each trace entry
+const traceStackTopCount = 3
+
+// Opcode of the error codepoint that ends every code segment
+const errorOpcode value.Opcode = 0x73
+
 type Machine struct {
 	c unsafe.Pointer
+
+	traceHandler machine.TraceHandler
+	profiler     *machine.Profiler
+	breakpoints  map[common.Hash]struct{}
 }
 
 func New(codeFile string) (*Machine, error) {
@@ -61,6 +76,65 @@ func New(codeFile string) (*Machine, error) {
 	return WrapCMachine(cMachine), nil
 }
 
+// NewChecked creates a machine from codeFile and returns an error if its
+// initial hash doesn't match expectedHash, usually the hash registered with
+// the rollup
+func NewChecked(codeFile string, expectedHash common.Hash) (*Machine, error) {
+	mach, err := New(codeFile)
+	if err != nil {
+		return nil, err
+	}
+	if hash := mach.Hash(); hash != expectedHash {
+		return nil, errors.Errorf(
+			"machine from file %s has hash %v but expected %v",
+			codeFile,
+			hash,
+			expectedHash,
+		)
+	}
+	return mach, nil
+}
+
+// NewFromBytes creates a machine from the contents of an executable file
+func NewFromBytes(data []byte) (*Machine, error) {
+	cData := toByteSliceView(data)
+	defer C.free(cData.data)
+	cMachine := C.machineCreateFromData(cData)
+	if cMachine == nil {
+		return nil, errors.New("error creating machine from data")
+	}
+	return WrapCMachine(cMachine), nil
+}
+
+// NewFromReader creates a machine from an executable read to completion from r
+func NewFromReader(r io.Reader) (*Machine, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading machine executable")
+	}
+	return NewFromBytes(data)
+}
+
+// NewFromCode creates a machine that executes code in order, starting with
+// its first operation, and ends at the error codepoint. With n operations,
+// code[i] has pc n-i in segment 0 and the error codepoint has pc 0, which is
+// how codepoint immediates in code and static refer to them.
+func NewFromCode(code []value.Operation, static value.Value) (*Machine, error) {
+	var buf bytes.Buffer
+	if err := machine.WriteExecutable(&buf, codeListing(code), static); err != nil {
+		return nil, err
+	}
+	return NewFromBytes(buf.Bytes())
+}
+
+func codeListing(code []value.Operation) []machine.CodeListingEntry {
+	listing := make([]machine.CodeListingEntry, 0, len(code)+1)
+	for i, op := range code {
+		listing = append(listing, machine.CodeListingEntry{PC: uint64(len(code) - i), Operation: op})
+	}
+	return append(listing, machine.CodeListingEntry{PC: 0, Operation: value.BasicOperation{Op: errorOpcode}})
+}
+
 func cdestroyVM(cMachine *Machine) {
 
 	C.machineDestroy(cMachine.c)
@@ -68,7 +142,7 @@ func cdestroyVM(cMachine *Machine) {
 }
 
 func WrapCMachine(cMachine unsafe.Pointer) *Machine {
-	ret := &Machine{cMachine}
+	ret := &Machine{c: cMachine}
 	runtime.SetFinalizer(ret, cdestroyVM)
 	return ret
 }
@@ -94,10 +168,23 @@ func (m *Machine) CodePointHash() (ret common.Hash) {
 	return
 }
 
+// ArbGasUsed returns the total ArbGas the machine has used since it was
+// created
+func (m *Machine) ArbGasUsed() *big.Int {
+	defer runtime.KeepAlive(m)
+	var ret [32]byte
+	C.machineArbGasUsed(m.c, unsafe.Pointer(&ret[0]))
+	return new(big.Int).SetBytes(ret[:])
+}
+
+// Clone returns an independent copy of the machine. Values are immutable and
+// shared between the copies, and the clone's code is layered over the
+// original's so neither machine observes the other's execution. Breakpoints,
+// trace handlers and profilers are not copied.
 func (m *Machine) Clone() machine.Machine {
 	defer runtime.KeepAlive(m)
 	cMachine := C.machineClone(m.c)
-	ret := &Machine{cMachine}
+	ret := &Machine{c: cMachine}
 	runtime.SetFinalizer(ret, cdestroyVM)
 	return ret
 }
@@ -121,7 +208,11 @@ func (m *Machine) CurrentStatus() machine.Status {
 func (m *Machine) IsBlocked(newMessages bool) machine.BlockReason {
 	defer runtime.KeepAlive(m)
 	cBlockReason := C.machineIsBlocked(m.c, boolToCInt(newMessages))
-	switch cBlockReason.blockType {
+	return blockReasonFromC(cBlockReason.blockType)
+}
+
+func blockReasonFromC(blockType C.enum_CBlockType) machine.BlockReason {
+	switch blockType {
 	case C.BLOCK_TYPE_NOT_BLOCKED:
 		return nil
 	case C.BLOCK_TYPE_HALT:
@@ -132,9 +223,11 @@ func (m *Machine) IsBlocked(newMessages bool) machine.BlockReason {
 		return machine.BreakpointBlocked{}
 	case C.BLOCK_TYPE_INBOX:
 		return machine.InboxBlocked{}
+	case C.BLOCK_TYPE_SIDELOAD:
+		return machine.SideloadBlocked{}
 	default:
+		panic("Unknown block type")
 	}
-	return nil
 }
 
 func (m *Machine) String() string {
@@ -245,6 +338,253 @@ func (m *Machine) ExecuteAssertionAdvanced(
 	return executionAssertion, values, steps, err
 }
 
+// RunWithGasLimit executes the machine without delivering any inbox messages
+// until it blocks or until executing the next instruction would consume more
+// than maxGas ArbGas. It returns the number of steps executed, the gas
+// consumed, and whether execution stopped because the gas limit was reached.
+// A maxGas of 0 runs the machine until it blocks.
+func (m *Machine) RunWithGasLimit(ctx context.Context, maxGas uint64) (uint64, uint64, bool, error) {
+	// The AVM compares its limit against the total gas the machine has ever
+	// used, so offset maxGas by what has been used already
+	limit := maxGas
+	if maxGas != 0 {
+		total := new(big.Int).Add(m.ArbGasUsed(), new(big.Int).SetUint64(maxGas))
+		if total.IsUint64() {
+			limit = total.Uint64()
+		} else {
+			limit = math.MaxUint64
+		}
+	}
+	assertion, _, steps, err := m.ExecuteAssertion(ctx, limit, false, nil, false)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	limitHit := maxGas != 0 && m.IsBlocked(false) == nil
+	return steps, assertion.NumGas, limitHit, nil
+}
+
+// SetTraceHandler registers a function called by Step with the state before
+// each instruction it executes. Blocked steps aren't traced. Passing nil
+// disables tracing. Pass the Add method of a machine.TraceBuffer to keep a
+// bounded trace of recent instructions.
+func (m *Machine) SetTraceHandler(handler machine.TraceHandler) {
+	m.traceHandler = handler
+}
+
+// SetProfiler makes Step and Run record every instruction executed in the
+// given profiler. Passing nil disables profiling.
+func (m *Machine) SetProfiler(profiler *machine.Profiler) {
+	m.profiler = profiler
+}
+
+// TracePoint describes the next instruction the machine will execute along
+// with the hashes of up to stackTopCount values from the top of the stack
+func (m *Machine) TracePoint(stackTopCount int) (machine.TraceEntry, error) {
+	defer runtime.KeepAlive(m)
+	point := C.machineTracePoint(m.c, C.int(stackTopCount))
+	if point.success == 0 {
+		return machine.TraceEntry{}, errors.New("failed to trace machine")
+	}
+	stackTopData := receiveByteSlice(point.stack_top)
+	stackTop := make([]common.Hash, 0, len(stackTopData)/32)
+	for i := 0; i+32 <= len(stackTopData); i += 32 {
+		var hash common.Hash
+		copy(hash[:], stackTopData[i:i+32])
+		stackTop = append(stackTop, hash)
+	}
+	return machine.TraceEntry{
+		Segment:      uint64(point.segment),
+		PC:           uint64(point.pc),
+		Opcode:       value.Opcode(point.opcode),
+		StackSize:    uint64(point.stack_size),
+		AuxStackSize: uint64(point.auxstack_size),
+		StackTop:     stackTop,
+	}, nil
+}
+
+// DeliverMessages appends messages to the inbox read by Step and Run, allowing
+// a machine blocked on an empty inbox to continue. ExecuteAssertion replaces
+// this inbox with the messages passed to it.
+func (m *Machine) DeliverMessages(messages []inbox.InboxMessage) error {
+	defer runtime.KeepAlive(m)
+	msgData := bytesArrayToByteSliceArray(encodeMachineInboxMessages(messages))
+	defer freeByteSliceArray(msgData)
+	if C.machineDeliverMessages(m.c, msgData) == 0 {
+		return errors.New("failed to deliver messages")
+	}
+	return nil
+}
+
+// Step executes the next instruction of the machine and returns its opcode.
+// If the machine was blocked and could not execute the instruction, the
+// reason is returned and the machine state is left unchanged. A halted or
+// errored machine reports HaltBlocked or ErrorBlocked respectively. Breakpoint
+// and sideload instructions never block a single step.
+func (m *Machine) Step() (value.Opcode, machine.BlockReason, error) {
+	opcode, blockReason, _, err := m.step(false, false, true)
+	return opcode, blockReason, err
+}
+
+func (m *Machine) step(stopOnBreakpoint, stopOnSideload, firstInstruction bool) (value.Opcode, machine.BlockReason, uint64, error) {
+	defer runtime.KeepAlive(m)
+	// The trace entry describes the state before the instruction, but is
+	// only passed on once the instruction has executed
+	var entry machine.TraceEntry
+	if m.traceHandler != nil || m.profiler != nil {
+		stackTopCount := 0
+		if m.traceHandler != nil {
+			stackTopCount = traceStackTopCount
+		}
+		var err error
+		entry, err = m.TracePoint(stackTopCount)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+	}
+	var codePointHash common.Hash
+	if m.profiler != nil {
+		codePointHash = m.CodePointHash()
+	}
+	result := C.machineStep(
+		m.c,
+		boolToCInt(stopOnBreakpoint),
+		boolToCInt(stopOnSideload),
+		boolToCInt(firstInstruction),
+	)
+	if result.success == 0 {
+		return 0, nil, 0, errors.New("failed to step machine")
+	}
+	opcode := value.Opcode(result.opcode)
+	blockReason := blockReasonFromC(result.blockType)
+	gas := uint64(result.gas_used)
+	if blockReason == nil {
+		if m.traceHandler != nil {
+			m.traceHandler(entry)
+		}
+		if m.profiler != nil {
+			m.profiler.Record(entry.Segment, entry.PC, codePointHash, opcode, gas)
+		}
+	}
+	return opcode, blockReason, gas, nil
+}
+
+// AddBreakpoint makes Run stop before executing the codepoint with the given
+// hash
+func (m *Machine) AddBreakpoint(codePointHash common.Hash) {
+	if m.breakpoints == nil {
+		m.breakpoints = make(map[common.Hash]struct{})
+	}
+	m.breakpoints[codePointHash] = struct{}{}
+}
+
+func (m *Machine) RemoveBreakpoint(codePointHash common.Hash) {
+	delete(m.breakpoints, codePointHash)
+}
+
+// Run executes up to maxSteps instructions one at a time, stopping early if
+// the machine blocks, requests a sideload or reaches a breakpoint, either a
+// breakpoint instruction or a codepoint added with AddBreakpoint. The result
+// reports why execution stopped along with the steps and gas consumed. The
+// instruction Run starts at never stops it, so calling Run again resumes
+// execution.
+func (m *Machine) Run(maxSteps uint64) (*machine.RunResult, error) {
+	return m.RunCtx(context.Background(), maxSteps)
+}
+
+// RunCtx is like Run but stops between instructions once ctx is done,
+// returning the progress made so far along with ctx.Err()
+func (m *Machine) RunCtx(ctx context.Context, maxSteps uint64) (*machine.RunResult, error) {
+	return m.run(ctx, maxSteps, true)
+}
+
+// RunUntilBlocked executes the machine until it halts, errors or blocks
+// waiting on the inbox or a sideload, ignoring any breakpoints. The result's
+// BlockReason is nil only if maxSteps instructions were executed without the
+// machine blocking.
+func (m *Machine) RunUntilBlocked(ctx context.Context, maxSteps uint64) (*machine.RunResult, error) {
+	return m.run(ctx, maxSteps, false)
+}
+
+func (m *Machine) run(ctx context.Context, maxSteps uint64, useBreakpoints bool) (*machine.RunResult, error) {
+	result, err := m.runSteps(ctx, maxSteps, useBreakpoints)
+	sends, logs, emissionsErr := m.takeEmissions()
+	if err == nil {
+		err = emissionsErr
+	}
+	result.Sends = sends
+	result.Logs = logs
+	return result, err
+}
+
+// takeEmissions returns the sends and logs emitted by the machine since it
+// was last called and clears them from the machine
+func (m *Machine) takeEmissions() ([][]byte, []value.Value, error) {
+	defer runtime.KeepAlive(m)
+	emissions := C.machineTakeEmissions(m.c)
+	if emissions.success == 0 {
+		return nil, nil, errors.New("failed to take machine emissions")
+	}
+	sendsRaw := receiveByteSlice(emissions.sends)
+	logsRaw := receiveByteSlice(emissions.logs)
+	assertion, err := protocol.NewExecutionAssertion(
+		0,
+		0,
+		sendsRaw,
+		uint64(emissions.send_count),
+		logsRaw,
+		uint64(emissions.log_count),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return assertion.Sends, assertion.Logs, nil
+}
+
+func (m *Machine) runSteps(ctx context.Context, maxSteps uint64, useBreakpoints bool) (*machine.RunResult, error) {
+	result := &machine.RunResult{}
+	var lastOpcode value.Opcode
+	stop := func(blockReason machine.BlockReason) *machine.RunResult {
+		result.BlockReason = blockReason
+		result.Reason = machine.StopReasonFromBlockReason(blockReason)
+		if result.Reason == machine.StopError && result.Steps > 0 {
+			result.ErrorOpcode = &lastOpcode
+		}
+		return result
+	}
+	for result.Steps < maxSteps {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+		if useBreakpoints && result.Steps > 0 && len(m.breakpoints) > 0 {
+			codePointHash := m.CodePointHash()
+			if _, ok := m.breakpoints[codePointHash]; ok {
+				return stop(machine.CodePointBreakpointBlocked{CodePointHash: codePointHash}), nil
+			}
+		}
+		opcode, blockReason, gas, err := m.step(useBreakpoints, true, result.Steps == 0)
+		if err != nil {
+			return result, err
+		}
+		if blockReason != nil {
+			return stop(blockReason), nil
+		}
+		lastOpcode = opcode
+		result.Steps++
+		result.Gas += gas
+	}
+	// The final instruction may have halted or errored the machine
+	switch m.CurrentStatus() {
+	case machine.ErrorStop:
+		return stop(machine.ErrorBlocked{}), nil
+	case machine.Halt:
+		return stop(machine.HaltBlocked{}), nil
+	default:
+		return stop(nil), nil
+	}
+}
+
 func (m *Machine) MarshalForProof() ([]byte, []byte, error) {
 	defer runtime.KeepAlive(m)
 	rawProof := C.machineMarshallForProof(m.c)
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmachine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+// ipfsGateway serves ipfs:// machine URLs over HTTPS. Fetching through a
+// gateway avoids running an IPFS node; the hash check makes trusting the
+// gateway unnecessary.
+const ipfsGateway = "https://ipfs.io/ipfs/"
+
+// resolveMachineURL maps ipfs://<cid>[/path] onto the HTTPS gateway and
+// returns any other url unchanged
+func resolveMachineURL(url string) string {
+	if strings.HasPrefix(url, "ipfs://") {
+		return ipfsGateway + strings.TrimPrefix(url, "ipfs://")
+	}
+	return url
+}
+
+// LoadMachineFromURL loads the machine executable at url, which may be an
+// http(s) or ipfs URL. The executable is cached in cacheDir by its expected
+// hash so it's only downloaded once. A cached executable that doesn't hash
+// to expectedHash is removed so the next load downloads it again.
+func LoadMachineFromURL(url string, cacheDir string, expectedHash common.Hash) (*Machine, error) {
+	filename := filepath.Join(cacheDir, expectedHash.String()+".mexe")
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		if err := configuration.DownloadMachine(resolveMachineURL(url), filename); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, errors.Wrap(err, "error checking machine cache")
+	}
+
+	mach, err := New(filename)
+	if err != nil {
+		return nil, err
+	}
+	if hash := mach.Hash(); hash != expectedHash {
+		if err := os.Remove(filename); err != nil {
+			return nil, errors.Wrap(err, "error removing mismatched machine from cache")
+		}
+		return nil, errors.Errorf(
+			"machine from %s has hash %v but expected %v",
+			url,
+			hash,
+			expectedHash,
+		)
+	}
+	return mach, nil
+}
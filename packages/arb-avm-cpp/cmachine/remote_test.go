@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmachine
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func TestLoadMachineFromURL(t *testing.T) {
+	data, err := ioutil.ReadFile(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	mach, err := New(codeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHash := mach.Hash()
+
+	cacheDir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		loaded, err := LoadMachineFromURL(server.URL, cacheDir, expectedHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Hash() != expectedHash {
+			t.Fatal("loaded machine has wrong hash")
+		}
+	}
+	if requests != 1 {
+		t.Fatal("expected one download but got", requests)
+	}
+
+	wrongHash := common.Hash{1}
+	if _, err := LoadMachineFromURL(server.URL, cacheDir, wrongHash); err == nil {
+		t.Fatal("expected hash mismatch error")
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, wrongHash.String()+".mexe")); !os.IsNotExist(err) {
+		t.Fatal("mismatched machine left in cache")
+	}
+}
+
+func TestLoadMachineFromURLKeepsUnreadableCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	expectedHash := common.Hash{2}
+	filename := filepath.Join(cacheDir, expectedHash.String()+".mexe")
+	if err := ioutil.WriteFile(filename, []byte("not a machine"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMachineFromURL("http://127.0.0.1:0", cacheDir, expectedHash); err == nil {
+		t.Fatal("expected error loading invalid machine")
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatal("cached machine removed after non-hash error")
+	}
+}
+
+func TestResolveMachineURL(t *testing.T) {
+	if url := resolveMachineURL("ipfs://QmTest/arbos.mexe"); url != ipfsGateway+"QmTest/arbos.mexe" {
+		t.Error("wrong ipfs url", url)
+	}
+	if url := resolveMachineURL("https://example.com/arbos.mexe"); url != "https://example.com/arbos.mexe" {
+		t.Error("wrong https url", url)
+	}
+}
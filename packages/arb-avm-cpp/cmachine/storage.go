@@ -23,6 +23,11 @@ package cmachine
 */
 import "C"
 import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
 	"unsafe"
 
@@ -34,7 +39,8 @@ import (
 )
 
 type ArbStorage struct {
-	c unsafe.Pointer
+	c      unsafe.Pointer
+	dbPath string
 }
 
 func boolToCInt(b bool) C.int {
@@ -121,7 +127,7 @@ func NewArbStorageWithFinalBlock(dbPath string, coreConfig *configuration.Core,
 		return nil, errors.Errorf("error creating ArbStorage %v", dbPath)
 	}
 
-	returnVal := &ArbStorage{cArbStorage}
+	returnVal := &ArbStorage{c: cArbStorage, dbPath: dbPath}
 	runtime.SetFinalizer(returnVal, cDestroyArbStorage)
 
 	return returnVal, nil
@@ -174,6 +180,87 @@ func (s *ArbStorage) CloseArbStorage() bool {
 	return C.closeArbStorage(s.c) == 1
 }
 
+// CreateCheckpoint writes a consistent copy of the database into checkpointDir,
+// which must not already exist. Writes may continue while the checkpoint is
+// being created. This uses the same rocksdb checkpoint as
+// ArbCore.SaveRocksdbCheckpoint, but runs synchronously on the caller's
+// goroutine, reports failure, and doesn't require the core thread to be
+// running or core.database.save-path to be configured.
+func (s *ArbStorage) CreateCheckpoint(checkpointDir string) error {
+	defer runtime.KeepAlive(s)
+	cCheckpointDir := C.CString(checkpointDir)
+	defer C.free(unsafe.Pointer(cCheckpointDir))
+	success := C.arbStorageCreateCheckpoint(s.c, cCheckpointDir)
+
+	if success == 0 {
+		return errors.Errorf("error creating database checkpoint in %v", checkpointDir)
+	}
+	return nil
+}
+
+// BackupTo writes a consistent snapshot of the entire database to w as a tar
+// stream without stopping the database. Extracting the stream into an empty
+// directory produces a database that can be opened with NewArbStorage.
+//
+// The snapshot is staged with CreateCheckpoint in a temporary directory that
+// is always removed before returning, whether or not writing the tar
+// succeeded.
+func (s *ArbStorage) BackupTo(w io.Writer) (err error) {
+	// Stage the checkpoint next to the database so rocksdb can hard link
+	// files instead of copying them
+	stagingDir, err := ioutil.TempDir(filepath.Dir(s.dbPath), "backup")
+	if err != nil {
+		return errors.Wrap(err, "error creating backup staging directory")
+	}
+	defer func() {
+		removeErr := os.RemoveAll(stagingDir)
+		if err == nil && removeErr != nil {
+			err = errors.Wrap(removeErr, "error removing backup staging directory")
+		}
+	}()
+
+	checkpointDir := filepath.Join(stagingDir, "db")
+	if err := s.CreateCheckpoint(checkpointDir); err != nil {
+		return err
+	}
+	return writeDirectoryTar(w, checkpointDir)
+}
+
+func writeDirectoryTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "error writing backup")
+	}
+	return tw.Close()
+}
+
 func cDestroyArbStorage(cArbStorage *ArbStorage) {
 	C.destroyArbStorage(cArbStorage.c)
 }
@@ -17,9 +17,16 @@
 package cmachine
 
 import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/pkg/errors"
+
 	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/gotest"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
 )
@@ -53,3 +60,85 @@ func TestCheckpoint(t *testing.T) {
 	}
 	defer arbStorage.CloseArbStorage()
 }
+
+func TestBackupTo(t *testing.T) {
+	dePath := "dbPath"
+
+	defer func() {
+		if err := os.RemoveAll(dePath); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	coreConfig := configuration.DefaultCoreSettingsMaxExecution()
+	arbStorage, err := NewArbStorage(dePath, coreConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := arbStorage.Initialize(codeFile); err != nil {
+		t.Fatal(err)
+	}
+	defer arbStorage.CloseArbStorage()
+
+	var buf bytes.Buffer
+	if err := arbStorage.BackupTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	foundCurrent := false
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == "CURRENT" {
+			foundCurrent = true
+		}
+	}
+	if !foundCurrent {
+		t.Error("backup missing rocksdb CURRENT file")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestBackupToCleansUpOnFailure(t *testing.T) {
+	parentDir, err := ioutil.TempDir("", "backup_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parentDir)
+	dbPath := filepath.Join(parentDir, "db")
+
+	coreConfig := configuration.DefaultCoreSettingsMaxExecution()
+	arbStorage, err := NewArbStorage(dbPath, coreConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := arbStorage.Initialize(codeFile); err != nil {
+		t.Fatal(err)
+	}
+	defer arbStorage.CloseArbStorage()
+
+	if err := arbStorage.BackupTo(failingWriter{}); err == nil {
+		t.Fatal("expected backup to failing writer to fail")
+	}
+
+	entries, err := ioutil.ReadDir(parentDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "db" {
+			t.Error("backup left behind", entry.Name())
+		}
+	}
+}
@@ -61,6 +61,8 @@ class ArbStorage {
     [[nodiscard]] std::unique_ptr<ReadWriteTransaction>
     makeReadWriteTransaction();
     rocksdb::Status cleanupValidator();
+    [[nodiscard]] rocksdb::Status createCheckpoint(
+        const std::string& checkpoint_dir);
 };
 
 #endif /* arbstorage_hpp */
@@ -66,6 +66,12 @@ rocksdb::Status ArbStorage::cleanupValidator() {
     return datastorage->cleanupValidator();
 }
 
+rocksdb::Status ArbStorage::createCheckpoint(
+    const std::string& checkpoint_dir) {
+    ReadTransaction tx(datastorage);
+    return tx.createRocksdbCheckpoint(checkpoint_dir);
+}
+
 std::unique_ptr<AggregatorStore> ArbStorage::getAggregatorStore() const {
     return std::make_unique<AggregatorStore>(datastorage);
 }
@@ -385,6 +385,17 @@ func (s *Staker) newStake(ctx context.Context) error {
 			return nil
 		}
 	}
+	// A new stake starts on the latest confirmed node, so don't stake if the
+	// local machine disagrees with it. A machine that hasn't caught up is
+	// checked by advanceStake before the stake is used.
+	latestConfirmed, err := s.rollup.LatestConfirmedNode(ctx)
+	if err != nil {
+		return err
+	}
+	err = VerifyNodeMachine(ctx, s.lookup, s.rollup.RollupWatcher, latestConfirmed)
+	if err != nil && !errors.Is(err, ErrMachineBehindNode) {
+		return err
+	}
 	stakeAmount, err := s.rollup.CurrentRequiredStake(ctx)
 	if err != nil {
 		return err
@@ -3,6 +3,7 @@ package staker
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -285,13 +286,9 @@ func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStake
 			return nil, false, err
 		}
 	}
-	cursorHash := cursor.MachineHash()
-	if err != nil {
+	if err := checkMachineHash(cursor, startState.ExecutionState); err != nil {
 		return nil, false, err
 	}
-	if cursorHash != startState.MachineHash {
-		return nil, false, errors.Errorf("local machine doesn't match chain %v %v", cursor.TotalGasConsumed(), startState.TotalGasConsumed)
-	}
 
 	if cursor.L2BlockNumber().BitLen() >= 128 {
 		if strategy == configuration.MakeNodesStrategy {
@@ -557,6 +554,77 @@ func (v *Validator) generateBatchEndProof(count *big.Int) ([]byte, error) {
 	return proof, nil
 }
 
+// MachineHashMismatchError reports that the local machine differs from the
+// machine asserted on chain after the same amount of gas
+type MachineHashMismatchError struct {
+	TotalGasConsumed *big.Int
+	LocalGasConsumed *big.Int
+	Local            common.Hash
+	Chain            common.Hash
+}
+
+func (e *MachineHashMismatchError) Error() string {
+	return fmt.Sprintf(
+		"local machine doesn't match chain after %v gas: local %v after %v gas, chain %v",
+		e.TotalGasConsumed,
+		e.Local,
+		e.LocalGasConsumed,
+		e.Chain,
+	)
+}
+
+// ErrMachineBehindNode is returned by VerifyNodeMachine when the local machine
+// hasn't yet read all the messages consumed by the node
+var ErrMachineBehindNode = errors.New("local machine hasn't reached node")
+
+func checkMachineHash(cursor core.ExecutionCursor, state *core.ExecutionState) error {
+	localHash := cursor.MachineHash()
+	if localHash != state.MachineHash {
+		return &MachineHashMismatchError{
+			TotalGasConsumed: state.TotalGasConsumed,
+			LocalGasConsumed: cursor.TotalGasConsumed(),
+			Local:            localHash,
+			Chain:            state.MachineHash,
+		}
+	}
+	return nil
+}
+
+// verifyMachineState confirms that the local machine matches state after
+// consuming the same amount of gas
+func verifyMachineState(lookup core.ArbCoreLookup, state *core.ExecutionState) error {
+	if lookup.MachineMessagesRead().Cmp(state.TotalMessagesRead) < 0 {
+		return ErrMachineBehindNode
+	}
+	cursor, err := lookup.GetExecutionCursor(state.TotalGasConsumed, true)
+	if err != nil {
+		return err
+	}
+	return checkMachineHash(cursor, state)
+}
+
+// VerifyNodeMachine confirms that the local machine matches the machine
+// asserted by rollup node nodeNum. It returns a *MachineHashMismatchError if
+// it doesn't, or ErrMachineBehindNode if the local machine can't be checked
+// yet.
+func VerifyNodeMachine(ctx context.Context, lookup core.ArbCoreLookup, rollup *ethbridge.RollupWatcher, nodeNum *big.Int) error {
+	var state *core.ExecutionState
+	if nodeNum.Sign() == 0 {
+		initialState, err := lookupNodeStartState(ctx, rollup, nodeNum, [32]byte{})
+		if err != nil {
+			return err
+		}
+		state = initialState.ExecutionState
+	} else {
+		node, err := rollup.LookupNode(ctx, nodeNum)
+		if err != nil {
+			return err
+		}
+		state = node.Assertion.After
+	}
+	return verifyMachineState(lookup, state)
+}
+
 func getBlockID(ctx context.Context, client ethutils.EthClient, number *big.Int) (*common.BlockId, error) {
 	blockInfo, err := client.BlockInfoByNumber(ctx, number)
 	if err != nil {
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+type stubCursor struct {
+	core.ExecutionCursor
+	machineHash common.Hash
+	gas         *big.Int
+}
+
+func (c *stubCursor) MachineHash() common.Hash {
+	return c.machineHash
+}
+
+func (c *stubCursor) TotalGasConsumed() *big.Int {
+	return c.gas
+}
+
+type stubLookup struct {
+	core.ArbCoreLookup
+	cursor       *stubCursor
+	cursorGas    *big.Int
+	messagesRead *big.Int
+}
+
+func (l *stubLookup) GetExecutionCursor(totalGasUsed *big.Int, _ bool) (core.ExecutionCursor, error) {
+	l.cursorGas = totalGasUsed
+	return l.cursor, nil
+}
+
+func (l *stubLookup) MachineMessagesRead() *big.Int {
+	return l.messagesRead
+}
+
+func testExecutionState() *core.ExecutionState {
+	return &core.ExecutionState{
+		TotalGasConsumed:  big.NewInt(1000),
+		MachineHash:       common.Hash{1},
+		TotalMessagesRead: big.NewInt(10),
+	}
+}
+
+func TestVerifyMachineStateMatch(t *testing.T) {
+	state := testExecutionState()
+	lookup := &stubLookup{
+		cursor:       &stubCursor{machineHash: common.Hash{1}, gas: big.NewInt(1000)},
+		messagesRead: big.NewInt(10),
+	}
+	if err := verifyMachineState(lookup, state); err != nil {
+		t.Fatal(err)
+	}
+	if lookup.cursorGas.Cmp(state.TotalGasConsumed) != 0 {
+		t.Error("cursor requested at wrong gas", lookup.cursorGas)
+	}
+}
+
+func TestVerifyMachineStateMismatch(t *testing.T) {
+	state := testExecutionState()
+	lookup := &stubLookup{
+		cursor:       &stubCursor{machineHash: common.Hash{2}, gas: big.NewInt(1000)},
+		messagesRead: big.NewInt(11),
+	}
+	err := verifyMachineState(lookup, state)
+	mismatch, ok := err.(*MachineHashMismatchError)
+	if !ok {
+		t.Fatal("expected machine hash mismatch but got", err)
+	}
+	if mismatch.TotalGasConsumed.Cmp(state.TotalGasConsumed) != 0 ||
+		mismatch.LocalGasConsumed.Cmp(big.NewInt(1000)) != 0 ||
+		mismatch.Local != (common.Hash{2}) ||
+		mismatch.Chain != (common.Hash{1}) {
+		t.Errorf("wrong mismatch details %+v", mismatch)
+	}
+}
+
+func TestVerifyMachineStateBehind(t *testing.T) {
+	lookup := &stubLookup{
+		cursor:       &stubCursor{machineHash: common.Hash{2}, gas: big.NewInt(0)},
+		messagesRead: big.NewInt(9),
+	}
+	if err := verifyMachineState(lookup, testExecutionState()); err != ErrMachineBehindNode {
+		t.Fatal("expected machine behind node but got", err)
+	}
+	if lookup.cursorGas != nil {
+		t.Error("requested cursor for machine that hasn't caught up")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"net/http"
 	"os"
@@ -778,22 +779,8 @@ func ParseNonRelay(ctx context.Context, f *flag.FlagSet, defaultWalletPathname s
 		// Machine does not exist, so load it from provided URL
 		logger.Debug().Str("URL", out.Rollup.Machine.URL).Msg("downloading machine")
 
-		resp, err := http.Get(out.Rollup.Machine.URL)
-		if err != nil {
-			return nil, nil, nil, nil, errors.Wrapf(err, "unable to get machine from: %s", out.Rollup.Machine.URL)
-		}
-		if resp.StatusCode != 200 {
-			return nil, nil, nil, nil, fmt.Errorf("HTTP status '%v' when trying to get machine from: %s", resp.Status, out.Rollup.Machine.URL)
-		}
-
-		fileOut, err := os.Create(out.Rollup.Machine.Filename)
-		if err != nil {
-			return nil, nil, nil, nil, errors.Wrapf(err, "unable to open file '%s' for machine", out.Rollup.Machine.Filename)
-		}
-
-		_, err = io.Copy(fileOut, resp.Body)
-		if err != nil {
-			return nil, nil, nil, nil, errors.Wrapf(err, "unable to output machine to: %s", out.Rollup.Machine.Filename)
+		if err := DownloadMachine(out.Rollup.Machine.URL, out.Rollup.Machine.Filename); err != nil {
+			return nil, nil, nil, nil, err
 		}
 	}
 
@@ -832,6 +819,39 @@ func ParseNonRelay(ctx context.Context, f *flag.FlagSet, defaultWalletPathname s
 	return out, wallet, l1Client, l1ChainId, nil
 }
 
+// DownloadMachine saves the machine at url to filename. The download is written
+// to a temporary file first so an interrupted download isn't mistaken for a
+// cached machine on the next start.
+func DownloadMachine(url string, filename string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get machine from: %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("HTTP status '%v' when trying to get machine from: %s", resp.Status, url)
+	}
+
+	fileOut, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".download")
+	if err != nil {
+		return errors.Wrapf(err, "unable to open temporary file for machine '%s'", filename)
+	}
+	defer os.Remove(fileOut.Name())
+
+	_, err = io.Copy(fileOut, resp.Body)
+	if closeErr := fileOut.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to output machine to: %s", filename)
+	}
+
+	if err := os.Rename(fileOut.Name(), filename); err != nil {
+		return errors.Wrapf(err, "unable to move machine to: %s", filename)
+	}
+	return nil
+}
+
 func resolveDirectoryNames(out *Config, wallet *Wallet) error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -17,6 +17,7 @@
 package inbox
 
 import (
+	"io"
 	"math/big"
 
 	"github.com/pkg/errors"
@@ -26,6 +27,10 @@ import (
 
 var errTupleSize2 = errors.New("expected 2-tuple value")
 
+// MaxByteArraySize is the largest byte array ReadByteArray and
+// WriteByteArray will handle
+const MaxByteArraySize = 1 << 24
+
 func ByteArrayToBytes(val value.Value) ([]byte, error) {
 	tupVal, ok := val.(*value.TupleValue)
 	if !ok || tupVal.Len() != 2 {
@@ -46,6 +51,84 @@ func ByteArrayToBytes(val value.Value) ([]byte, error) {
 	return BufAndLengthToBytes(sizeInt.BigInt(), contentsBuffer)
 }
 
+// BytesToByteArray converts data into the (size, buffer) byte array
+// representation read by ByteArrayToBytes
+func BytesToByteArray(data []byte) *value.TupleValue {
+	contents := make([]byte, len(data))
+	copy(contents, data)
+	return value.NewTuple2(
+		value.NewInt64Value(int64(len(data))),
+		value.NewBuffer(contents),
+	)
+}
+
+// byteArrayChunkSize is how much ReadByteArray reads from its reader at a time
+const byteArrayChunkSize = 1 << 16
+
+// ReadByteArray reads r to the end and returns its contents as a byte array
+// value. It reads in chunks and fails as soon as a chunk would take the
+// contents past MaxByteArraySize, so it never holds more than that.
+func ReadByteArray(r io.Reader) (*value.TupleValue, error) {
+	var data []byte
+	chunk := make([]byte, byteArrayChunkSize)
+	for {
+		n, err := r.Read(chunk)
+		if len(data)+n > MaxByteArraySize {
+			return nil, errors.Errorf("byte array larger than maximum size %v", MaxByteArraySize)
+		}
+		data = append(data, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value.NewTuple2(value.NewInt64Value(int64(len(data))), value.NewBuffer(data)), nil
+}
+
+// WriteByteArray writes the contents of a byte array value to w, padding the
+// contents of its buffer with zeros up to its size. Byte arrays larger than
+// MaxByteArraySize or with a negative size are rejected.
+func WriteByteArray(w io.Writer, val value.Value) error {
+	tupVal, ok := val.(*value.TupleValue)
+	if !ok || tupVal.Len() != 2 {
+		return errors.New("expected byte array to be 2 tuple")
+	}
+	sizeVal, _ := tupVal.GetByInt64(0)
+	contents, _ := tupVal.GetByInt64(1)
+
+	sizeInt, ok := sizeVal.(value.IntValue)
+	if !ok {
+		return errors.New("byte array size must be an int")
+	}
+	contentsBuffer, ok := contents.(*value.Buffer)
+	if !ok {
+		return errors.New("contents must be an buffer")
+	}
+
+	if sizeInt.BigInt().Sign() < 0 {
+		return errors.Errorf("byte array size %v is negative", sizeInt.BigInt())
+	}
+	if sizeInt.BigInt().Cmp(big.NewInt(MaxByteArraySize)) > 0 {
+		return errors.Errorf("byte array size %v larger than maximum %v", sizeInt.BigInt(), MaxByteArraySize)
+	}
+	size := sizeInt.BigInt().Uint64()
+	data := contentsBuffer.Data()
+	if uint64(len(data)) > size {
+		return errors.Errorf("buffer too small, size=%v, length=%v", size, len(data))
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padding := size - uint64(len(data)); padding > 0 {
+		if _, err := w.Write(make([]byte, padding)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func BufAndLengthToBytes(sizeInt *big.Int, contents *value.Buffer) ([]byte, error) {
 	size := sizeInt.Uint64()
 	if uint64(len(contents.Data())) > size {
@@ -17,6 +17,8 @@
 package inbox
 
 import (
+	"bytes"
+	"math/big"
 	"testing"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
@@ -57,3 +59,95 @@ func TestStackValueToListFailures(t *testing.T) {
 		t.Error("should fail when passed tuple not of size 2")
 	}
 }
+
+func TestByteArrayRoundTrip(t *testing.T) {
+	data := []byte("arbitrary data")
+	byteArray, err := ReadByteArray(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := ByteArrayToBytes(byteArray)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Errorf("expected %x but got %x", data, data2)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteByteArray(&buf, byteArray); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("expected %x but wrote %x", data, buf.Bytes())
+	}
+}
+
+func TestWriteByteArrayPadding(t *testing.T) {
+	byteArray := value.NewTuple2(value.NewInt64Value(10000), value.NewBuffer([]byte{1, 2, 3}))
+	var buf bytes.Buffer
+	if err := WriteByteArray(&buf, byteArray); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ByteArrayToBytes(byteArray)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Error("written byte array doesn't match ByteArrayToBytes")
+	}
+}
+
+func TestByteArrayLimits(t *testing.T) {
+	if _, err := ReadByteArray(bytes.NewReader(make([]byte, MaxByteArraySize))); err != nil {
+		t.Error("failed to read maximum size byte array", err)
+	}
+	if _, err := ReadByteArray(bytes.NewReader(make([]byte, MaxByteArraySize+1))); err == nil {
+		t.Error("read byte array larger than maximum")
+	}
+
+	oversized := value.NewTuple2(value.NewInt64Value(MaxByteArraySize+1), value.NewBuffer(nil))
+	var buf bytes.Buffer
+	if err := WriteByteArray(&buf, oversized); err == nil {
+		t.Error("wrote byte array larger than maximum")
+	}
+	if buf.Len() != 0 {
+		t.Error("wrote data before rejecting oversized byte array")
+	}
+
+	huge := value.NewTuple2(value.NewIntValue(new(big.Int).Lsh(big.NewInt(1), 64)), value.NewBuffer(nil))
+	if err := WriteByteArray(&buf, huge); err == nil {
+		t.Error("wrote byte array with size overflowing uint64")
+	}
+
+	negative := value.NewTuple2(value.NewInt64Value(-1), value.NewBuffer(nil))
+	if err := WriteByteArray(&buf, negative); err == nil {
+		t.Error("wrote byte array with negative size")
+	}
+	if buf.Len() != 0 {
+		t.Error("wrote data before rejecting negative byte array size")
+	}
+}
+
+// endlessReader produces zeros forever and counts how many it has produced
+type endlessReader struct {
+	count int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	r.count += len(p)
+	return len(p), nil
+}
+
+func TestReadByteArrayStopsAtLimit(t *testing.T) {
+	r := &endlessReader{}
+	if _, err := ReadByteArray(r); err == nil {
+		t.Fatal("read endless byte array")
+	}
+	if r.count > MaxByteArraySize+byteArrayChunkSize {
+		t.Errorf("read %v bytes before rejecting oversized byte array", r.count)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// CodeAnalysis summarizes a code listing without executing it
+type CodeAnalysis struct {
+	OpcodeCounts map[value.Opcode]uint64
+	// Operations whose opcode isn't defined by the AVM
+	InvalidOpcodes []CodeListingEntry
+	// Operations with an immediate referencing a codepoint that isn't in the
+	// listing
+	InvalidJumps []CodeListingEntry
+	// Operations that can't be reached by falling through from the start of
+	// the listing and aren't referenced by any immediate or the static value
+	Unreachable []CodeListingEntry
+	// Static stack effects of each straight-line block of code, in listing
+	// order
+	Blocks []BlockStackEffect
+	// Largest Required and Growth of any block
+	MaxStackRequired    int
+	MaxStackGrowth      int
+	MaxAuxStackRequired int
+	MaxAuxStackGrowth   int
+}
+
+// StackBounds is the static effect of a block of code on one stack
+type StackBounds struct {
+	// Depth needed on entry so that no operation pops an empty stack
+	Required int
+	// Largest height reached above the depth on entry
+	Growth int
+	// Height on exit relative to the depth on entry
+	Delta int
+}
+
+func (b *StackBounds) apply(pops int, pushes int) {
+	b.Delta -= pops
+	if -b.Delta > b.Required {
+		b.Required = -b.Delta
+	}
+	b.Delta += pushes
+	if b.Delta > b.Growth {
+		b.Growth = b.Delta
+	}
+}
+
+// BlockStackEffect is the stack effect of a block of code. A block starts at
+// the beginning of the listing, at each referenced codepoint and after each
+// jump, cjump, error or halt, and runs until the next block starts.
+type BlockStackEffect struct {
+	Start    CodeListingEntry
+	Length   int
+	Stack    StackBounds
+	AuxStack StackBounds
+}
+
+// AnalyzeCode examines listing, which must be in execution order as returned
+// by Disassemble, along with the machine's static value. Codepoints are only
+// considered referenced if they appear as a value in an immediate or the
+// static, so code only reached through computed codepoints is reported as
+// unreachable.
+func AnalyzeCode(listing []CodeListingEntry, static value.Value) *CodeAnalysis {
+	analysis := &CodeAnalysis{OpcodeCounts: make(map[value.Opcode]uint64)}
+
+	positions := make(map[codePointKey]struct{}, len(listing))
+	for _, entry := range listing {
+		positions[codePointKey{segment: entry.Segment, pc: entry.PC}] = struct{}{}
+	}
+
+	referenced := make(map[codePointKey]struct{})
+	if static != nil {
+		collectCodePoints(static, referenced)
+	}
+	for _, entry := range listing {
+		opcode := entry.Operation.GetOp()
+		analysis.OpcodeCounts[opcode]++
+		if !opcode.IsValid() {
+			analysis.InvalidOpcodes = append(analysis.InvalidOpcodes, entry)
+		}
+
+		immOp, ok := entry.Operation.(value.ImmediateOperation)
+		if !ok {
+			continue
+		}
+		targets := make(map[codePointKey]struct{})
+		collectCodePoints(immOp.Val, targets)
+		for target := range targets {
+			referenced[target] = struct{}{}
+			if _, ok := positions[target]; !ok && target.pc != 0 {
+				// pc 0 is the error codepoint, which exists in every segment
+				analysis.InvalidJumps = append(analysis.InvalidJumps, entry)
+				break
+			}
+		}
+	}
+
+	reachable := true
+	for i, entry := range listing {
+		_, isReferenced := referenced[codePointKey{segment: entry.Segment, pc: entry.PC}]
+		startsBlock := i == 0 || isReferenced
+		if i > 0 {
+			prev := listing[i-1].Operation.GetOp()
+			reachable = reachable && fallsThrough(prev)
+			startsBlock = startsBlock || !fallsThrough(prev) || prev.Name() == "cjump"
+		}
+		if isReferenced {
+			reachable = true
+		}
+		if !reachable {
+			analysis.Unreachable = append(analysis.Unreachable, entry)
+		}
+
+		if startsBlock {
+			analysis.Blocks = append(analysis.Blocks, BlockStackEffect{Start: entry})
+		}
+		block := &analysis.Blocks[len(analysis.Blocks)-1]
+		block.Length++
+		if _, ok := entry.Operation.(value.ImmediateOperation); ok {
+			block.Stack.apply(0, 1)
+		}
+		effect := entry.Operation.GetOp().StackEffect()
+		block.Stack.apply(effect.Pops, effect.Pushes)
+		block.AuxStack.apply(effect.AuxPops, effect.AuxPushes)
+	}
+
+	for _, block := range analysis.Blocks {
+		analysis.MaxStackRequired = maxInt(analysis.MaxStackRequired, block.Stack.Required)
+		analysis.MaxStackGrowth = maxInt(analysis.MaxStackGrowth, block.Stack.Growth)
+		analysis.MaxAuxStackRequired = maxInt(analysis.MaxAuxStackRequired, block.AuxStack.Required)
+		analysis.MaxAuxStackGrowth = maxInt(analysis.MaxAuxStackGrowth, block.AuxStack.Growth)
+	}
+	return analysis
+}
+
+// fallsThrough returns whether execution can continue to the next operation
+// after op
+func fallsThrough(op value.Opcode) bool {
+	switch op.Name() {
+	case "jump", "error", "halt":
+		return false
+	default:
+		return true
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func collectCodePoints(val value.Value, points map[codePointKey]struct{}) {
+	switch val := val.(type) {
+	case value.CodePointStub:
+		points[codePointKey{segment: val.Segment, pc: val.PC}] = struct{}{}
+	case value.CodePointValue:
+		if immOp, ok := val.Op.(value.ImmediateOperation); ok {
+			collectCodePoints(immOp.Val, points)
+		}
+	case *value.TupleValue:
+		for _, item := range val.Contents() {
+			collectCodePoints(item, points)
+		}
+	}
+}
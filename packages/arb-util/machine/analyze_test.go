@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func TestAnalyzeCode(t *testing.T) {
+	op := func(pc uint64, opcode value.Opcode) CodeListingEntry {
+		return CodeListingEntry{PC: pc, Operation: value.BasicOperation{Op: opcode}}
+	}
+	immOp := func(pc uint64, opcode value.Opcode, val value.Value) CodeListingEntry {
+		return CodeListingEntry{PC: pc, Operation: value.ImmediateOperation{Op: opcode, Val: val}}
+	}
+	listing := []CodeListingEntry{
+		immOp(6, 0x34, value.CodePointStub{PC: 3}),
+		// Only reachable by falling through the jump
+		op(5, 0x3b),
+		op(4, 0xff),
+		op(3, 0x3b),
+		immOp(2, 0x34, value.NewTuple2(value.NewInt64Value(1), value.CodePointStub{PC: 9})),
+		op(1, 0x3b),
+		op(0, 0x73),
+	}
+	static := value.NewTuple2(value.CodePointStub{PC: 1}, value.NewInt64Value(0))
+
+	analysis := AnalyzeCode(listing, static)
+	if analysis.OpcodeCounts[0x3b] != 3 || analysis.OpcodeCounts[0x34] != 2 {
+		t.Error("wrong opcode counts", analysis.OpcodeCounts)
+	}
+	if len(analysis.InvalidOpcodes) != 1 || analysis.InvalidOpcodes[0].PC != 4 {
+		t.Error("wrong invalid opcodes", analysis.InvalidOpcodes)
+	}
+	if len(analysis.InvalidJumps) != 1 || analysis.InvalidJumps[0].PC != 2 {
+		t.Error("wrong invalid jumps", analysis.InvalidJumps)
+	}
+	if len(analysis.Unreachable) != 2 || analysis.Unreachable[0].PC != 5 || analysis.Unreachable[1].PC != 4 {
+		t.Error("wrong unreachable code", analysis.Unreachable)
+	}
+}
+
+func TestAnalyzeStackEffects(t *testing.T) {
+	listing := []CodeListingEntry{
+		{PC: 5, Operation: value.BasicOperation{Op: 0x40}},
+		{PC: 4, Operation: value.ImmediateOperation{Op: 0x01, Val: value.NewInt64Value(1)}},
+		{PC: 3, Operation: value.BasicOperation{Op: 0x38}},
+		{PC: 2, Operation: value.BasicOperation{Op: 0x43}},
+		{PC: 1, Operation: value.BasicOperation{Op: 0x74}},
+		{PC: 0, Operation: value.BasicOperation{Op: 0x73}},
+	}
+	analysis := AnalyzeCode(listing, nil)
+	if len(analysis.Blocks) != 2 {
+		t.Fatal("wrong blocks", analysis.Blocks)
+	}
+	block := analysis.Blocks[0]
+	if block.Start.PC != 5 || block.Length != 5 {
+		t.Error("wrong first block", block)
+	}
+	if block.Stack != (StackBounds{Required: 2, Growth: 2, Delta: 0}) {
+		t.Error("wrong stack effect", block.Stack)
+	}
+	if block.AuxStack != (StackBounds{Required: 0, Growth: 1, Delta: 1}) {
+		t.Error("wrong aux stack effect", block.AuxStack)
+	}
+	if analysis.Blocks[1].Start.PC != 0 || analysis.Blocks[1].Length != 1 {
+		t.Error("wrong second block", analysis.Blocks[1])
+	}
+	if analysis.MaxStackRequired != 2 || analysis.MaxStackGrowth != 2 ||
+		analysis.MaxAuxStackRequired != 0 || analysis.MaxAuxStackGrowth != 1 {
+		t.Error("wrong maximum stack effects", analysis)
+	}
+}
@@ -16,6 +16,12 @@
 
 package machine
 
+import (
+	"fmt"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
 type BlockReason interface {
 	IsBlocked(m Machine, newMessages bool) bool
 	Equals(b BlockReason) bool
@@ -69,6 +75,25 @@ func (b BreakpointBlocked) Equals(a BlockReason) bool {
 	return ok
 }
 
+// CodePointBreakpointBlocked is reported when execution reaches a codepoint
+// registered as a breakpoint on the machine
+type CodePointBreakpointBlocked struct {
+	CodePointHash common.Hash
+}
+
+func (b CodePointBreakpointBlocked) String() string {
+	return fmt.Sprintf("CodePointBreakpointBlocked(%v)", b.CodePointHash)
+}
+
+func (b CodePointBreakpointBlocked) IsBlocked(Machine, bool) bool {
+	return false
+}
+
+func (b CodePointBreakpointBlocked) Equals(a BlockReason) bool {
+	other, ok := a.(CodePointBreakpointBlocked)
+	return ok && other.CodePointHash == b.CodePointHash
+}
+
 type InboxBlocked struct {
 }
 
@@ -84,3 +109,21 @@ func (b InboxBlocked) Equals(a BlockReason) bool {
 	_, ok := a.(InboxBlocked)
 	return ok
 }
+
+// SideloadBlocked is reported when the machine requests a sideload that
+// hasn't been provided
+type SideloadBlocked struct {
+}
+
+func (b SideloadBlocked) String() string {
+	return "SideloadBlocked"
+}
+
+func (b SideloadBlocked) IsBlocked(Machine, bool) bool {
+	return false
+}
+
+func (b SideloadBlocked) Equals(a BlockReason) bool {
+	_, ok := a.(SideloadBlocked)
+	return ok
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// Executable format version written by WriteExecutable
+const executableVersion = 2
+
+type executableOperation struct {
+	Opcode    value.Opcode    `json:"opcode"`
+	Immediate json.RawMessage `json:"immediate"`
+}
+
+type executable struct {
+	Version    uint64                `json:"version"`
+	Code       []executableOperation `json:"code"`
+	StaticVal  json.RawMessage       `json:"static_val"`
+	Extensions []string              `json:"extensions"`
+}
+
+// WriteExecutable writes a code segment and static value in the compiler's
+// executable format, which cmachine can load. The listing must be in execution
+// order and run contiguously down to the segment's error codepoint at pc 0, as
+// returned by Disassemble. Debug info isn't preserved.
+func WriteExecutable(w io.Writer, listing []CodeListingEntry, static value.Value) error {
+	if len(listing) == 0 || listing[len(listing)-1].PC != 0 {
+		return errors.New("listing must end at the error codepoint")
+	}
+	segment := listing[0].Segment
+	opCount := uint64(len(listing) - 1)
+	for i, entry := range listing {
+		if entry.Segment != segment || entry.PC != opCount-uint64(i) {
+			return errors.Errorf("listing isn't contiguous at %v:%v", entry.Segment, entry.PC)
+		}
+	}
+
+	encoder := executableEncoder{segment: segment, opCount: opCount}
+	exe := executable{
+		Version:    executableVersion,
+		Code:       make([]executableOperation, 0, opCount),
+		Extensions: []string{},
+	}
+	for _, entry := range listing[:opCount] {
+		op := executableOperation{Opcode: entry.Operation.GetOp(), Immediate: json.RawMessage("null")}
+		if immOp, ok := entry.Operation.(value.ImmediateOperation); ok {
+			imm, err := encoder.encode(immOp.Val)
+			if err != nil {
+				return errors.Wrapf(err, "invalid immediate at %v:%v", entry.Segment, entry.PC)
+			}
+			op.Immediate = imm
+		}
+		exe.Code = append(exe.Code, op)
+	}
+	staticVal, err := encoder.encode(static)
+	if err != nil {
+		return errors.Wrap(err, "invalid static value")
+	}
+	exe.StaticVal = staticVal
+	return json.NewEncoder(w).Encode(exe)
+}
+
+type executableEncoder struct {
+	segment uint64
+	opCount uint64
+}
+
+func (e executableEncoder) encode(val value.Value) (json.RawMessage, error) {
+	switch val := val.(type) {
+	case value.IntValue:
+		return json.Marshal(map[string]string{"Int": val.BigInt().Text(16)})
+	case *value.Buffer:
+		return json.Marshal(map[string]string{"Buffer": hex.EncodeToString(val.Data())})
+	case *value.TupleValue:
+		contents := make([]json.RawMessage, 0, len(val.Contents()))
+		for _, item := range val.Contents() {
+			encoded, err := e.encode(item)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, encoded)
+		}
+		return json.Marshal(map[string][]json.RawMessage{"Tuple": contents})
+	case value.CodePointStub:
+		if val.Segment != e.segment || val.PC > e.opCount {
+			return nil, errors.Errorf("codepoint %v:%v is outside the listing", val.Segment, val.PC)
+		}
+		// The compiler references codepoints by their offset from the start
+		// of the code, using the maximum offset for the error codepoint
+		offset := uint64(math.MaxUint64)
+		if val.PC != 0 {
+			offset = e.opCount - val.PC
+		}
+		return json.Marshal(map[string]map[string]uint64{"CodePoint": {"Internal": offset}})
+	default:
+		return nil, errors.Errorf("can't encode %T in an executable", val)
+	}
+}
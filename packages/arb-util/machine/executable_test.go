@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func TestWriteExecutable(t *testing.T) {
+	listing := []CodeListingEntry{
+		{PC: 3, Operation: value.ImmediateOperation{Op: 0x34, Val: value.CodePointStub{PC: 1}}},
+		{PC: 2, Operation: value.ImmediateOperation{Op: 0x30, Val: value.NewBuffer([]byte{0xab})}},
+		{PC: 1, Operation: value.BasicOperation{Op: 0x3b}},
+		{PC: 0, Operation: value.BasicOperation{Op: 0x73}},
+	}
+	static := value.NewTuple2(value.NewInt64Value(255), value.CodePointStub{})
+
+	var buf bytes.Buffer
+	if err := WriteExecutable(&buf, listing, static); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":2,"code":[` +
+		`{"opcode":52,"immediate":{"CodePoint":{"Internal":2}}},` +
+		`{"opcode":48,"immediate":{"Buffer":"ab"}},` +
+		`{"opcode":59,"immediate":null}],` +
+		`"static_val":{"Tuple":[{"Int":"ff"},{"CodePoint":{"Internal":18446744073709551615}}]},` +
+		`"extensions":[]}`
+	if strings.TrimSpace(buf.String()) != expected {
+		t.Error("wrong executable", buf.String())
+	}
+
+	if err := WriteExecutable(&buf, listing[1:], value.CodePointStub{PC: 3}); err == nil {
+		t.Error("expected error for codepoint outside the listing")
+	}
+	if err := WriteExecutable(&buf, listing[:3], static); err == nil {
+		t.Error("expected error for listing without error codepoint")
+	}
+}
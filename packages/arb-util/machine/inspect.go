@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// Inspection is a read-only snapshot of a machine's state intended for
+// debugging
+type Inspection struct {
+	CodePointHash common.Hash
+	Segment       uint64
+	PC            uint64
+	Operation     value.Operation
+	Register      value.Value
+	Static        value.Value
+	StackSize     uint64
+	AuxStackSize  uint64
+	// Values at the top of each stack, top first. These may be fewer than
+	// the stack size.
+	Stack    []value.Value
+	AuxStack []value.Value
+}
+
+// Format renders the snapshot, truncating each value's representation to
+// maxValueLength characters with value.Format. A maxValueLength of 0 disables
+// truncation.
+func (i *Inspection) Format(maxValueLength int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "codepoint: %v:%v %v (%v)\n", i.Segment, i.PC, i.Operation, i.CodePointHash)
+	fmt.Fprintf(&sb, "register: %v\n", value.Format(i.Register, 0, maxValueLength))
+	fmt.Fprintf(&sb, "static: %v\n", value.Format(i.Static, 0, maxValueLength))
+	fmt.Fprintf(&sb, "stack (%v values):\n", i.StackSize)
+	for j, val := range i.Stack {
+		fmt.Fprintf(&sb, "  %v: %v\n", j, value.Format(val, 0, maxValueLength))
+	}
+	fmt.Fprintf(&sb, "auxstack (%v values):\n", i.AuxStackSize)
+	for j, val := range i.AuxStack {
+		fmt.Fprintf(&sb, "  %v: %v\n", j, value.Format(val, 0, maxValueLength))
+	}
+	return sb.String()
+}
+
+// CodeListingEntry is an operation at a position in the machine's code
+type CodeListingEntry struct {
+	Segment   uint64
+	PC        uint64
+	Operation value.Operation
+}
+
+func (e CodeListingEntry) String() string {
+	return fmt.Sprintf("%v:%v\t%v", e.Segment, e.PC, value.Disassemble(e.Operation, 2, 80))
+}
+
+// StateDifference describes a component of the machine state that differs
+// between two inspections
+type StateDifference struct {
+	Component string
+	// Differing subvalues, if the component is a value
+	Values []value.Difference
+	// Both sides of the mismatch, if the component isn't a value
+	A interface{}
+	B interface{}
+}
+
+func (d StateDifference) String() string {
+	if d.Values == nil {
+		return fmt.Sprintf("%v: %v != %v", d.Component, d.A, d.B)
+	}
+	parts := make([]string, 0, len(d.Values))
+	for _, diff := range d.Values {
+		parts = append(parts, diff.String())
+	}
+	return fmt.Sprintf("%v: %v", d.Component, strings.Join(parts, ", "))
+}
+
+// CompareInspections returns the components that differ between a and b,
+// starting with the codepoint. Only the stack values captured by both
+// inspections are compared.
+func CompareInspections(a, b *Inspection) []StateDifference {
+	var diffs []StateDifference
+	addValue := func(component string, valA, valB value.Value) {
+		if valueDiffs := value.Diff(valA, valB); len(valueDiffs) > 0 {
+			diffs = append(diffs, StateDifference{Component: component, Values: valueDiffs})
+		}
+	}
+	addOther := func(component string, valA, valB interface{}) {
+		if valA != valB {
+			diffs = append(diffs, StateDifference{Component: component, A: valA, B: valB})
+		}
+	}
+	addOther("codepoint", fmt.Sprintf("%v:%v", a.Segment, a.PC), fmt.Sprintf("%v:%v", b.Segment, b.PC))
+	addOther("codepoint hash", a.CodePointHash, b.CodePointHash)
+	addValue("register", a.Register, b.Register)
+	addValue("static", a.Static, b.Static)
+	addOther("stack size", a.StackSize, b.StackSize)
+	for i := 0; i < len(a.Stack) && i < len(b.Stack); i++ {
+		addValue(fmt.Sprintf("stack[%v]", i), a.Stack[i], b.Stack[i])
+	}
+	addOther("auxstack size", a.AuxStackSize, b.AuxStackSize)
+	for i := 0; i < len(a.AuxStack) && i < len(b.AuxStack); i++ {
+		addValue(fmt.Sprintf("auxstack[%v]", i), a.AuxStack[i], b.AuxStack[i])
+	}
+	return diffs
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func TestCompareInspections(t *testing.T) {
+	a := &Inspection{
+		PC:        5,
+		Register:  value.NewTuple2(value.NewInt64Value(1), value.NewInt64Value(2)),
+		Static:    value.NewEmptyTuple(),
+		StackSize: 2,
+		Stack:     []value.Value{value.NewInt64Value(3), value.NewInt64Value(4)},
+	}
+	b := &Inspection{
+		PC:        5,
+		Register:  value.NewTuple2(value.NewInt64Value(1), value.NewInt64Value(7)),
+		Static:    value.NewEmptyTuple(),
+		StackSize: 3,
+		Stack:     []value.Value{value.NewInt64Value(3)},
+	}
+
+	if diffs := CompareInspections(a, a); len(diffs) != 0 {
+		t.Fatal("identical inspections differ", diffs)
+	}
+	diffs := CompareInspections(a, b)
+	if len(diffs) != 2 {
+		t.Fatal("wrong number of differences", diffs)
+	}
+	if diffs[0].Component != "register" || len(diffs[0].Values) != 1 || diffs[0].Values[0].Path[0] != 1 {
+		t.Error("wrong register difference", diffs[0])
+	}
+	if diffs[1].String() != "stack size: 2 != 3" {
+		t.Error("wrong stack size difference", diffs[1])
+	}
+}
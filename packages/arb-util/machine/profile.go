@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// ProfileEntry counts the instructions executed and ArbGas they consumed
+type ProfileEntry struct {
+	Count uint64
+	Gas   uint64
+}
+
+type OpcodeProfile struct {
+	Opcode value.Opcode
+	ProfileEntry
+}
+
+// CodePointProfile identifies a codepoint by the same segment and pc as
+// TraceEntry, so it can be looked up in a SymbolTable
+type CodePointProfile struct {
+	Segment       uint64
+	PC            uint64
+	CodePointHash common.Hash
+	Opcode        value.Opcode
+	ProfileEntry
+}
+
+// Profiler accumulates instruction counts and gas usage per opcode and per
+// codepoint
+type Profiler struct {
+	opcodes    map[value.Opcode]*ProfileEntry
+	codePoints map[codePointKey]*CodePointProfile
+	total      ProfileEntry
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		opcodes:    make(map[value.Opcode]*ProfileEntry),
+		codePoints: make(map[codePointKey]*CodePointProfile),
+	}
+}
+
+// Record adds an instruction executed at the given codepoint to the profile
+func (p *Profiler) Record(segment uint64, pc uint64, codePointHash common.Hash, opcode value.Opcode, gas uint64) {
+	opEntry, ok := p.opcodes[opcode]
+	if !ok {
+		opEntry = &ProfileEntry{}
+		p.opcodes[opcode] = opEntry
+	}
+	opEntry.Count++
+	opEntry.Gas += gas
+
+	key := codePointKey{segment: segment, pc: pc}
+	cpEntry, ok := p.codePoints[key]
+	if !ok {
+		cpEntry = &CodePointProfile{
+			Segment:       segment,
+			PC:            pc,
+			CodePointHash: codePointHash,
+			Opcode:        opcode,
+		}
+		p.codePoints[key] = cpEntry
+	}
+	cpEntry.Count++
+	cpEntry.Gas += gas
+
+	p.total.Count++
+	p.total.Gas += gas
+}
+
+// Reset discards everything recorded so far
+func (p *Profiler) Reset() {
+	p.opcodes = make(map[value.Opcode]*ProfileEntry)
+	p.codePoints = make(map[codePointKey]*CodePointProfile)
+	p.total = ProfileEntry{}
+}
+
+// ProfileReport is a snapshot of a profile with opcodes and codepoints
+// ordered by descending gas usage
+type ProfileReport struct {
+	Total      ProfileEntry
+	Opcodes    []OpcodeProfile
+	CodePoints []CodePointProfile
+}
+
+func (p *Profiler) Report() *ProfileReport {
+	report := &ProfileReport{
+		Total:      p.total,
+		Opcodes:    make([]OpcodeProfile, 0, len(p.opcodes)),
+		CodePoints: make([]CodePointProfile, 0, len(p.codePoints)),
+	}
+	for opcode, entry := range p.opcodes {
+		report.Opcodes = append(report.Opcodes, OpcodeProfile{Opcode: opcode, ProfileEntry: *entry})
+	}
+	for _, entry := range p.codePoints {
+		report.CodePoints = append(report.CodePoints, *entry)
+	}
+	sort.Slice(report.Opcodes, func(i, j int) bool {
+		a, b := report.Opcodes[i], report.Opcodes[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		return a.Opcode < b.Opcode
+	})
+	sort.Slice(report.CodePoints, func(i, j int) bool {
+		a, b := report.CodePoints[i], report.CodePoints[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		if a.Segment != b.Segment {
+			return a.Segment < b.Segment
+		}
+		return a.PC < b.PC
+	})
+	return report
+}
+
+// Format renders the report with at most limit codepoints listed
+func (r *ProfileReport) Format(limit int) string {
+	return r.FormatWithSymbols(limit, nil)
+}
+
+// FormatWithSymbols is like Format but also shows the source location of each
+// codepoint found in symbols, which may be nil
+func (r *ProfileReport) FormatWithSymbols(limit int, symbols *SymbolTable) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "total: %v instructions, %v gas\n", r.Total.Count, r.Total.Gas)
+	sb.WriteString("by opcode:\n")
+	for _, entry := range r.Opcodes {
+		fmt.Fprintf(&sb, "  0x%02x: %v instructions, %v gas\n", entry.Opcode, entry.Count, entry.Gas)
+	}
+	sb.WriteString("by codepoint:\n")
+	for i, entry := range r.CodePoints {
+		if i == limit {
+			fmt.Fprintf(&sb, "  ... %v more\n", len(r.CodePoints)-limit)
+			break
+		}
+		fmt.Fprintf(&sb, "  %v (0x%02x): %v instructions, %v gas", entry.CodePointHash, entry.Opcode, entry.Count, entry.Gas)
+		if symbols != nil {
+			if loc, ok := symbols.Lookup(entry.Segment, entry.PC); ok {
+				fmt.Fprintf(&sb, " at %v", loc)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
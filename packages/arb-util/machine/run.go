@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// StopReason describes why a run of the machine ended
+type StopReason int
+
+const (
+	StopStepLimit StopReason = iota
+	StopHalt
+	StopError
+	StopInbox
+	StopBreakpoint
+	StopSideload
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopStepLimit:
+		return "StepLimit"
+	case StopHalt:
+		return "Halt"
+	case StopError:
+		return "Error"
+	case StopInbox:
+		return "Inbox"
+	case StopBreakpoint:
+		return "Breakpoint"
+	case StopSideload:
+		return "Sideload"
+	default:
+		return fmt.Sprintf("StopReason(%d)", int(r))
+	}
+}
+
+// StopReasonFromBlockReason maps the reason a machine was blocked to the
+// corresponding stop reason
+func StopReasonFromBlockReason(blockReason BlockReason) StopReason {
+	switch blockReason.(type) {
+	case HaltBlocked:
+		return StopHalt
+	case ErrorBlocked:
+		return StopError
+	case InboxBlocked:
+		return StopInbox
+	case BreakpointBlocked, CodePointBreakpointBlocked:
+		return StopBreakpoint
+	case SideloadBlocked:
+		return StopSideload
+	default:
+		return StopStepLimit
+	}
+}
+
+// RunResult summarizes a run of the machine
+type RunResult struct {
+	Reason StopReason
+	// BlockReason is the reason the machine cannot continue running, or nil
+	// if it stopped only because the step limit was reached
+	BlockReason BlockReason
+	// ErrorOpcode is the opcode of the instruction that moved the machine
+	// into the error state, if that happened during the run
+	ErrorOpcode *value.Opcode
+	Steps       uint64
+	Gas         uint64
+	// Outgoing messages and logs emitted since the previous run, including
+	// any emitted by instructions executed individually with Step
+	Sends [][]byte
+	Logs  []value.Value
+}
+
+func (r *RunResult) String() string {
+	if r.ErrorOpcode != nil {
+		return fmt.Sprintf("RunResult(%v, op 0x%x, steps %v, gas %v)", r.Reason, *r.ErrorOpcode, r.Steps, r.Gas)
+	}
+	return fmt.Sprintf("RunResult(%v, steps %v, gas %v)", r.Reason, r.Steps, r.Gas)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// SourceLocation is the position in the compiler's input that produced an
+// instruction, along with the function containing it if known
+type SourceLocation struct {
+	File     string
+	Line     uint64
+	Column   uint64
+	Function string
+}
+
+func (l SourceLocation) String() string {
+	if l.Function != "" {
+		return fmt.Sprintf("%v (%v:%v:%v)", l.Function, l.File, l.Line, l.Column)
+	}
+	return fmt.Sprintf("%v:%v:%v", l.File, l.Line, l.Column)
+}
+
+type codePointKey struct {
+	segment uint64
+	pc      uint64
+}
+
+// SymbolTable maps the codepoints of a loaded executable to the source
+// locations recorded in its debug info. Segment and PC match the fields of
+// TraceEntry and CodeListingEntry.
+type SymbolTable struct {
+	locations map[codePointKey]SourceLocation
+}
+
+type executableDebugInfo struct {
+	Code []struct {
+		DebugInfo *struct {
+			Location *struct {
+				Line   uint64 `json:"line"`
+				Column uint64 `json:"column"`
+				FileID uint64 `json:"file_id"`
+			} `json:"location"`
+			Function string `json:"function"`
+		} `json:"debug_info"`
+	} `json:"code"`
+	FileNameChart map[string]string `json:"file_name_chart"`
+	FileInfoChart map[string]string `json:"file_info_chart"`
+}
+
+// LoadSymbolTable reads the debug info from a compiled executable, or from a
+// sidecar file using the same layout. Compilers don't record function names,
+// but a sidecar file may add them to each operation's debug_info as
+// "function".
+func LoadSymbolTable(r io.Reader) (*SymbolTable, error) {
+	var info executableDebugInfo
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return nil, errors.Wrap(err, "error decoding debug info")
+	}
+
+	files := make(map[uint64]string)
+	for _, chart := range []map[string]string{info.FileNameChart, info.FileInfoChart} {
+		for id, name := range chart {
+			fileID, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid file id %v", id)
+			}
+			files[fileID] = name
+		}
+	}
+
+	table := &SymbolTable{locations: make(map[codePointKey]SourceLocation)}
+	for i, op := range info.Code {
+		if op.DebugInfo == nil || op.DebugInfo.Location == nil {
+			continue
+		}
+		loc := op.DebugInfo.Location
+		file, ok := files[loc.FileID]
+		if !ok {
+			file = strconv.FormatUint(loc.FileID, 10)
+		}
+		// Executables are loaded into segment 0 in reverse with the error
+		// codepoint at pc 0, so the first operation has the highest pc
+		key := codePointKey{segment: 0, pc: uint64(len(info.Code) - i)}
+		table.locations[key] = SourceLocation{
+			File:     file,
+			Line:     loc.Line,
+			Column:   loc.Column,
+			Function: op.DebugInfo.Function,
+		}
+	}
+	return table, nil
+}
+
+func LoadSymbolTableFromFile(path string) (*SymbolTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	return LoadSymbolTable(f)
+}
+
+// Lookup returns the source location of a codepoint if it has one
+func (t *SymbolTable) Lookup(segment uint64, pc uint64) (SourceLocation, bool) {
+	loc, ok := t.locations[codePointKey{segment: segment, pc: pc}]
+	return loc, ok
+}
+
+// Len returns the number of codepoints with a known source location
+func (t *SymbolTable) Len() int {
+	return len(t.locations)
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func TestLoadSymbolTable(t *testing.T) {
+	executable := `{
+		"version": 2,
+		"code": [
+			{"opcode": 59, "immediate": null, "debug_info": {"location": {"line": 3, "column": 5, "file_id": 12655948870391487324}}},
+			{"opcode": 60, "immediate": null, "debug_info": {"location": null}},
+			{"opcode": 61, "immediate": null, "debug_info": {"location": {"line": 8, "column": 1, "file_id": 7}, "function": "main"}}
+		],
+		"static_val": {"Tuple": []},
+		"file_name_chart": {"12655948870391487324": "builtin/array.mini"}
+	}`
+	table, err := LoadSymbolTable(strings.NewReader(executable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 2 {
+		t.Fatal("wrong number of locations", table.Len())
+	}
+
+	loc, ok := table.Lookup(0, 3)
+	if !ok {
+		t.Fatal("missing location of first operation")
+	}
+	if loc.String() != "builtin/array.mini:3:5" {
+		t.Error("wrong location", loc)
+	}
+	if _, ok := table.Lookup(0, 2); ok {
+		t.Error("operation without location has one")
+	}
+	loc, ok = table.Lookup(0, 1)
+	if !ok || loc.String() != "main (7:8:1)" {
+		t.Error("wrong location for unknown file", loc)
+	}
+}
+
+func TestSymbolizeProfile(t *testing.T) {
+	executable := `{
+		"code": [
+			{"opcode": 59, "immediate": null, "debug_info": {"location": {"line": 3, "column": 5, "file_id": 1}, "function": "loop"}},
+			{"opcode": 52, "immediate": null, "debug_info": {"location": {"line": 4, "column": 9, "file_id": 1}, "function": "loop"}}
+		],
+		"file_name_chart": {"1": "main.mini"}
+	}`
+	table, err := LoadSymbolTable(strings.NewReader(executable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiler := NewProfiler()
+	for i := 0; i < 3; i++ {
+		profiler.Record(0, 2, common.Hash{2}, 0x3b, 1)
+		profiler.Record(0, 1, common.Hash{1}, 0x34, 4)
+	}
+	// Executed code missing from the symbol table
+	profiler.Record(1, 7, common.Hash{3}, 0x3b, 1)
+
+	report := profiler.Report()
+	if len(report.CodePoints) != 3 {
+		t.Fatal("wrong number of codepoints", report.CodePoints)
+	}
+	top := report.CodePoints[0]
+	loc, ok := table.Lookup(top.Segment, top.PC)
+	if !ok || loc.String() != "loop (main.mini:4:9)" {
+		t.Error("wrong location for most expensive codepoint", loc)
+	}
+	formatted := report.FormatWithSymbols(10, table)
+	for _, expected := range []string{"at loop (main.mini:4:9)", "at loop (main.mini:3:5)"} {
+		if !strings.Contains(formatted, expected) {
+			t.Errorf("formatted report missing %v:\n%v", expected, formatted)
+		}
+	}
+	if strings.Count(formatted, " at ") != 2 {
+		t.Errorf("unexpected locations in formatted report:\n%v", formatted)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// TraceEntry describes the machine immediately before it executes an
+// instruction
+type TraceEntry struct {
+	Segment      uint64
+	PC           uint64
+	Opcode       value.Opcode
+	StackSize    uint64
+	AuxStackSize uint64
+	// Hashes of the values at the top of the data stack, top first
+	StackTop []common.Hash
+}
+
+func (e TraceEntry) String() string {
+	return fmt.Sprintf(
+		"pc %v:%v op 0x%x stack %v aux %v top %v",
+		e.Segment,
+		e.PC,
+		e.Opcode,
+		e.StackSize,
+		e.AuxStackSize,
+		e.StackTop,
+	)
+}
+
+type TraceHandler func(TraceEntry)
+
+// TraceBuffer keeps the most recent trace entries up to a fixed capacity. Its
+// Add method can be passed directly as a TraceHandler.
+type TraceBuffer struct {
+	entries []TraceEntry
+	next    int
+	full    bool
+}
+
+// NewTraceBuffer creates a buffer keeping the last size entries. A size of 0
+// or less disables tracing, so Add does nothing and Entries is always empty.
+func NewTraceBuffer(size int) *TraceBuffer {
+	if size < 0 {
+		size = 0
+	}
+	return &TraceBuffer{entries: make([]TraceEntry, size)}
+}
+
+func (b *TraceBuffer) Add(entry TraceEntry) {
+	if len(b.entries) == 0 {
+		return
+	}
+	b.entries[b.next] = entry
+	b.next++
+	if b.next == len(b.entries) {
+		b.next = 0
+		b.full = true
+	}
+}
+
+// Entries returns the buffered trace entries, oldest first
+func (b *TraceBuffer) Entries() []TraceEntry {
+	if !b.full {
+		return append([]TraceEntry(nil), b.entries[:b.next]...)
+	}
+	ret := make([]TraceEntry, 0, len(b.entries))
+	ret = append(ret, b.entries[b.next:]...)
+	return append(ret, b.entries[:b.next]...)
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package machine
+
+import (
+	"testing"
+)
+
+func TestTraceBuffer(t *testing.T) {
+	buffer := NewTraceBuffer(2)
+	for pc := uint64(0); pc < 3; pc++ {
+		buffer.Add(TraceEntry{PC: pc})
+	}
+	entries := buffer.Entries()
+	if len(entries) != 2 || entries[0].PC != 1 || entries[1].PC != 2 {
+		t.Error("wrong buffered entries", entries)
+	}
+}
+
+func TestTraceBufferDisabled(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		buffer := NewTraceBuffer(size)
+		buffer.Add(TraceEntry{PC: 1})
+		if entries := buffer.Entries(); len(entries) != 0 {
+			t.Errorf("buffer of size %v kept entries %v", size, entries)
+		}
+	}
+}
@@ -24,30 +24,39 @@ import (
 )
 
 type CodePointStub struct {
-	PC   uint64
-	hash common.Hash
+	Segment uint64
+	PC      uint64
+	hash    common.Hash
 }
 
 func NewCodePointStubFromReader(rd io.Reader) (CodePointStub, error) {
+	var segment uint64
+	if err := binary.Read(rd, binary.BigEndian, &segment); err != nil {
+		return CodePointStub{}, err
+	}
 	var insnNum uint64
 	if err := binary.Read(rd, binary.BigEndian, &insnNum); err != nil {
 		return CodePointStub{}, err
 	}
 	var hash common.Hash
-	if _, err := rd.Read(hash[:]); err != nil {
+	if _, err := io.ReadFull(rd, hash[:]); err != nil {
 		return CodePointStub{}, err
 	}
 	return CodePointStub{
-		PC:   insnNum,
-		hash: hash,
+		Segment: segment,
+		PC:      insnNum,
+		hash:    hash,
 	}, nil
 }
 
 func (cp CodePointStub) String() string {
-	return fmt.Sprintf("CodePointStub(%v, %v)", cp.PC, cp.hash)
+	return fmt.Sprintf("CodePointStub(%v, %v, %v)", cp.Segment, cp.PC, cp.hash)
 }
 
 func (cp CodePointStub) Marshal(w io.Writer) error {
+	if err := binary.Write(w, binary.BigEndian, &cp.Segment); err != nil {
+		return err
+	}
 	if err := binary.Write(w, binary.BigEndian, &cp.PC); err != nil {
 		return err
 	}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Difference describes a position at which two values differ. Path is the
+// chain of tuple indexes leading from the root to the differing values.
+type Difference struct {
+	Path []int
+	A    Value
+	B    Value
+}
+
+func (d Difference) String() string {
+	path := make([]string, 0, len(d.Path))
+	for _, index := range d.Path {
+		path = append(path, fmt.Sprint(index))
+	}
+	return fmt.Sprintf(
+		"[%v]: %v != %v",
+		strings.Join(path, "."),
+		Format(d.A, 2, 100),
+		Format(d.B, 2, 100),
+	)
+}
+
+// Diff returns the smallest subvalues at which a and b differ, in order.
+// Tuples of the same length are compared element by element; any other
+// mismatch is reported as a whole. Diff returns nil if a and b are equal.
+func Diff(a, b Value) []Difference {
+	return diff(a, b, nil, nil)
+}
+
+func diff(a, b Value, path []int, diffs []Difference) []Difference {
+	tupA, okA := a.(*TupleValue)
+	tupB, okB := b.(*TupleValue)
+	if okA && okB && tupA.Len() == tupB.Len() {
+		for i := range tupA.Contents() {
+			diffs = diff(tupA.contentsArr[i], tupB.contentsArr[i], append(path, i), diffs)
+		}
+		return diffs
+	}
+	if a.Equal(b) {
+		return diffs
+	}
+	return append(diffs, Difference{
+		Path: append([]int(nil), path...),
+		A:    a,
+		B:    b,
+	})
+}
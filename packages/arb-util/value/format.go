@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"fmt"
+	"strings"
+)
+
+type formatter struct {
+	sb       strings.Builder
+	maxDepth int
+	maxWidth int
+}
+
+func (f *formatter) full() bool {
+	return f.maxWidth > 0 && f.sb.Len() >= f.maxWidth
+}
+
+func (f *formatter) write(str string) {
+	if f.maxWidth > 0 && f.sb.Len()+len(str) > f.maxWidth {
+		str = str[:f.maxWidth-f.sb.Len()]
+	}
+	f.sb.WriteString(str)
+}
+
+func (f *formatter) format(v Value, depth int) {
+	if f.full() {
+		return
+	}
+	switch v := v.(type) {
+	case *TupleValue:
+		if f.maxDepth > 0 && depth >= f.maxDepth {
+			f.write(fmt.Sprintf("Tuple<%v>(...)", v.Len()))
+			return
+		}
+		f.write("Tuple(")
+		for i, child := range v.Contents() {
+			if i > 0 {
+				f.write(", ")
+			}
+			f.format(child, depth+1)
+		}
+		f.write(")")
+	case CodePointValue:
+		if op, ok := v.Op.(ImmediateOperation); ok {
+			f.write(fmt.Sprintf("CodePoint(0x%x Imd(", op.Op))
+			f.format(op.Val, depth+1)
+			f.write("))")
+			return
+		}
+		f.write(v.String())
+	case *Buffer:
+		data := v.Data()
+		if f.maxWidth > 0 && 2*len(data) > f.maxWidth {
+			// Avoid hex encoding more of a large buffer than can be shown
+			data = data[:f.maxWidth/2+1]
+		}
+		f.write(fmt.Sprintf("Buffer(0x%x)", data))
+	default:
+		f.write(v.String())
+	}
+}
+
+// Format renders v like its String method, but replaces tuples nested more
+// than maxDepth levels deep with a placeholder and truncates the result to
+// maxWidth characters, ending it with "..." if anything was cut. A limit of 0
+// disables it. The work done is bounded by the limits rather than by the size
+// of v.
+func Format(v Value, maxDepth int, maxWidth int) string {
+	f := &formatter{maxDepth: maxDepth, maxWidth: maxWidth}
+	if maxWidth > 0 {
+		// Render one character past the limit to detect truncation
+		f.maxWidth = maxWidth + 1
+	}
+	f.format(v, 0)
+	str := f.sb.String()
+	if maxWidth > 0 && len(str) > maxWidth {
+		return str[:maxWidth] + "..."
+	}
+	return str
+}
@@ -43,7 +43,20 @@ func NewIntValue(x *big.Int) IntValue {
 	return IntValue{x}
 }
 
+// Preallocated values for small integers, which are by far the most common.
+// IntValue never modifies its big.Int, so these can be shared.
+var smallIntValues = func() [256]IntValue {
+	var vals [256]IntValue
+	for i := range vals {
+		vals[i] = IntValue{big.NewInt(int64(i))}
+	}
+	return vals
+}()
+
 func NewInt64Value(x int64) IntValue {
+	if x >= 0 && x < int64(len(smallIntValues)) {
+		return smallIntValues[x]
+	}
 	return IntValue{big.NewInt(x)}
 }
 
@@ -55,14 +68,28 @@ func NewValueFromAddress(addr common.Address) IntValue {
 
 func NewIntValueFromReader(rd io.Reader) (IntValue, error) {
 	var data common.Hash
-	_, err := rd.Read(data[:])
+	_, err := io.ReadFull(rd, data[:])
 	if err != nil {
 		return IntValue{}, err
 	}
+	if isSmallInt(data) {
+		return smallIntValues[data[31]], nil
+	}
 	ret := new(big.Int).SetBytes(data[:])
 	return NewIntValue(ret), err
 }
 
+// isSmallInt returns whether the big endian integer in data has a
+// preallocated value in smallIntValues
+func isSmallInt(data common.Hash) bool {
+	for _, b := range data[:31] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func (iv IntValue) TypeCode() uint8 {
 	return TypeCodeInt
 }
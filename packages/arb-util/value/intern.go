@@ -0,0 +1,110 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"crypto/sha256"
+	"io"
+)
+
+// InternTable maps structurally identical values to a single shared instance.
+// Interned tuples are shared by pointer, so comparing two values interned in
+// the same table with Equal returns as soon as it reaches a shared subtree.
+// An InternTable is not safe for concurrent use.
+type InternTable struct {
+	values map[[32]byte]Value
+}
+
+func NewInternTable() *InternTable {
+	return &InternTable{values: make(map[[32]byte]Value)}
+}
+
+// Len returns the number of distinct values in the table
+func (t *InternTable) Len() int {
+	return len(t.values)
+}
+
+// Intern returns the table's instance of v, adding v and its subvalues to the
+// table if they weren't present
+func (t *InternTable) Intern(v Value) Value {
+	ret, _ := t.intern(v)
+	return ret
+}
+
+func (t *InternTable) intern(v Value) (Value, [32]byte) {
+	h := sha256.New()
+	var build func() Value
+	switch val := v.(type) {
+	case *TupleValue:
+		h.Write([]byte{val.TypeCode()})
+		var contents [MaxTupleSize]Value
+		for i, child := range val.Contents() {
+			internedChild, key := t.intern(child)
+			contents[i] = internedChild
+			h.Write(key[:])
+		}
+		build = func() Value {
+			// Contents came from a valid tuple
+			tup, _ := NewTupleOfSizeWithContents(contents, int8(val.Len()))
+			return tup
+		}
+	case CodePointValue:
+		op, ok := val.Op.(ImmediateOperation)
+		if !ok {
+			writeLeafKey(h, v)
+			build = func() Value { return v }
+			break
+		}
+		internedImmediate, key := t.intern(op.Val)
+		h.Write([]byte{val.TypeCode(), 1, byte(op.Op)})
+		h.Write(key[:])
+		h.Write(val.NextHash[:])
+		build = func() Value {
+			return CodePointValue{
+				Op:       ImmediateOperation{Op: op.Op, Val: internedImmediate},
+				NextHash: val.NextHash,
+			}
+		}
+	default:
+		writeLeafKey(h, v)
+		build = func() Value { return v }
+	}
+
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	if existing, ok := t.values[key]; ok {
+		return existing, key
+	}
+	ret := build()
+	t.values[key] = ret
+	return ret, key
+}
+
+func writeLeafKey(w io.Writer, v Value) {
+	// Writes to a hash never fail and leaves are always marshalable
+	_ = MarshalValue(v, w)
+}
+
+// UnmarshalValueInterned reads a value like UnmarshalValue and interns it in
+// the given table
+func UnmarshalValueInterned(r io.Reader, table *InternTable) (Value, error) {
+	val, err := UnmarshalValue(r)
+	if err != nil {
+		return nil, err
+	}
+	return table.Intern(val), nil
+}
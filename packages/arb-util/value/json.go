@@ -0,0 +1,188 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"encoding/json"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// MarshalValueJSON encodes values as an object with a single key naming the
+// value type:
+//
+//	{"int": "0x2a"}
+//	{"tuple": [<value>, ...]}
+//	{"buffer": "0x0102"}
+//	{"codepoint": {"opcode": 52, "immediate": <value>, "nextHash": "0x..."}}
+//	{"hashPreImage": {"hash": "0x...", "size": 3}}
+//	{"codePointStub": {"segment": 0, "pc": 5, "hash": "0x..."}}
+//
+// The immediate is omitted for operations without one. Integers and byte
+// strings use the hex encoding from hexutil.
+type jsonValue struct {
+	Int           *hexutil.Big       `json:"int,omitempty"`
+	Tuple         *[]json.RawMessage `json:"tuple,omitempty"`
+	Buffer        *hexutil.Bytes     `json:"buffer,omitempty"`
+	CodePoint     *jsonCodePoint     `json:"codepoint,omitempty"`
+	HashPreImage  *jsonHashPreImage  `json:"hashPreImage,omitempty"`
+	CodePointStub *jsonCodePointStub `json:"codePointStub,omitempty"`
+}
+
+type jsonCodePoint struct {
+	Opcode    Opcode           `json:"opcode"`
+	Immediate *json.RawMessage `json:"immediate,omitempty"`
+	NextHash  ethcommon.Hash   `json:"nextHash"`
+}
+
+type jsonHashPreImage struct {
+	Hash ethcommon.Hash `json:"hash"`
+	Size int64          `json:"size"`
+}
+
+type jsonCodePointStub struct {
+	Segment uint64         `json:"segment"`
+	PC      uint64         `json:"pc"`
+	Hash    ethcommon.Hash `json:"hash"`
+}
+
+// JSONValue wraps a Value so that it's encoded with MarshalValueJSON. The
+// value types don't implement json.Marshaler themselves, so structs and log
+// fields holding a Value keep their existing encoding unless they opt in by
+// using JSONValue.
+type JSONValue struct {
+	Value
+}
+
+func (v JSONValue) MarshalJSON() ([]byte, error) {
+	return MarshalValueJSON(v.Value)
+}
+
+func (v *JSONValue) UnmarshalJSON(data []byte) error {
+	val, err := UnmarshalValueJSON(data)
+	if err != nil {
+		return err
+	}
+	v.Value = val
+	return nil
+}
+
+// MarshalValueJSON encodes a value of any type so that it can be decoded with
+// UnmarshalValueJSON
+func MarshalValueJSON(val Value) ([]byte, error) {
+	jsonVal, err := newJSONValue(val)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonVal)
+}
+
+func newJSONValue(val Value) (jsonValue, error) {
+	switch val := val.(type) {
+	case IntValue:
+		return jsonValue{Int: (*hexutil.Big)(val.BigInt())}, nil
+	case *TupleValue:
+		contents := make([]json.RawMessage, 0, val.Len())
+		for _, child := range val.Contents() {
+			data, err := MarshalValueJSON(child)
+			if err != nil {
+				return jsonValue{}, err
+			}
+			contents = append(contents, data)
+		}
+		return jsonValue{Tuple: &contents}, nil
+	case *Buffer:
+		data := hexutil.Bytes(val.Data())
+		return jsonValue{Buffer: &data}, nil
+	case CodePointValue:
+		cp := &jsonCodePoint{
+			Opcode:   val.Op.GetOp(),
+			NextHash: ethcommon.Hash(val.NextHash),
+		}
+		if op, ok := val.Op.(ImmediateOperation); ok {
+			data, err := MarshalValueJSON(op.Val)
+			if err != nil {
+				return jsonValue{}, err
+			}
+			immediate := json.RawMessage(data)
+			cp.Immediate = &immediate
+		}
+		return jsonValue{CodePoint: cp}, nil
+	case HashPreImage:
+		return jsonValue{HashPreImage: &jsonHashPreImage{
+			Hash: ethcommon.Hash(val.hashImage),
+			Size: val.size,
+		}}, nil
+	case CodePointStub:
+		return jsonValue{CodePointStub: &jsonCodePointStub{
+			Segment: val.Segment,
+			PC:      val.PC,
+			Hash:    ethcommon.Hash(val.hash),
+		}}, nil
+	default:
+		return jsonValue{}, errors.Errorf("can't encode value of type %T as json", val)
+	}
+}
+
+// UnmarshalValueJSON decodes a value of any type from its JSON encoding
+func UnmarshalValueJSON(data []byte) (Value, error) {
+	var val jsonValue
+	if err := json.Unmarshal(data, &val); err != nil {
+		return nil, err
+	}
+	switch {
+	case val.Int != nil:
+		return NewIntValue((*big.Int)(val.Int)), nil
+	case val.Tuple != nil:
+		contents := make([]Value, 0, len(*val.Tuple))
+		for _, raw := range *val.Tuple {
+			child, err := UnmarshalValueJSON(raw)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, child)
+		}
+		return NewTupleFromSlice(contents)
+	case val.Buffer != nil:
+		return NewBuffer(*val.Buffer), nil
+	case val.CodePoint != nil:
+		var op Operation = BasicOperation{Op: val.CodePoint.Opcode}
+		if val.CodePoint.Immediate != nil {
+			immediate, err := UnmarshalValueJSON(*val.CodePoint.Immediate)
+			if err != nil {
+				return nil, err
+			}
+			op = ImmediateOperation{Op: val.CodePoint.Opcode, Val: immediate}
+		}
+		return CodePointValue{Op: op, NextHash: common.NewHashFromEth(val.CodePoint.NextHash)}, nil
+	case val.HashPreImage != nil:
+		return NewPreImage(common.NewHashFromEth(val.HashPreImage.Hash), val.HashPreImage.Size), nil
+	case val.CodePointStub != nil:
+		return CodePointStub{
+			Segment: val.CodePointStub.Segment,
+			PC:      val.CodePointStub.PC,
+			hash:    common.NewHashFromEth(val.CodePointStub.Hash),
+		}, nil
+	default:
+		return nil, errors.New("unknown value type in json")
+	}
+}
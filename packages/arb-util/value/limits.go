@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// LimitExceededError is returned by UnmarshalValueWithLimits when the encoded
+// value is nested more deeply or is larger than allowed
+type LimitExceededError struct {
+	Limit string
+	Max   int64
+}
+
+func (e LimitExceededError) Error() string {
+	return fmt.Sprintf("value exceeds maximum %v of %v", e.Limit, e.Max)
+}
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	maxSize   int64
+	maxDepth  int
+}
+
+// Read reads at most the remaining number of bytes. It only fails with a
+// LimitExceededError if more data is needed once the limit has been reached.
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if lr.remaining <= 0 {
+		return 0, LimitExceededError{Limit: "size", Max: lr.maxSize}
+	}
+	if int64(len(p)) > lr.remaining {
+		p = p[:lr.remaining]
+	}
+	n, err := lr.r.Read(p)
+	lr.remaining -= int64(n)
+	return n, err
+}
+
+// UnmarshalValueWithLimits decodes a value like UnmarshalValue, but fails with
+// a LimitExceededError if the value is nested more than maxDepth levels deep
+// or its encoding is longer than maxSize bytes. Nothing larger than maxSize is
+// allocated, so it is safe to use on data from untrusted peers.
+func UnmarshalValueWithLimits(r io.Reader, maxDepth int, maxSize int64) (Value, error) {
+	lr := &limitedReader{r: r, remaining: maxSize, maxSize: maxSize, maxDepth: maxDepth}
+	return unmarshalValueWithLimits(lr, maxDepth)
+}
+
+func unmarshalValueWithLimits(lr *limitedReader, depth int) (Value, error) {
+	if depth <= 0 {
+		return nil, LimitExceededError{Limit: "depth", Max: int64(lr.maxDepth)}
+	}
+	tipe := make([]byte, 1)
+	if _, err := io.ReadFull(lr, tipe); err != nil {
+		return nil, err
+	}
+	switch {
+	case tipe[0] >= TypeCodeTuple && tipe[0] <= TypeCodeTuple+MaxTupleSize:
+		size := int(tipe[0] - TypeCodeTuple)
+		contents := make([]Value, 0, size)
+		for i := 0; i < size; i++ {
+			val, err := unmarshalValueWithLimits(lr, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, val)
+		}
+		return NewTupleFromSlice(contents)
+	case tipe[0] == TypeCodeBuffer:
+		var length uint64
+		if err := binary.Read(lr, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+		if length > uint64(lr.remaining) {
+			return nil, LimitExceededError{Limit: "size", Max: lr.maxSize}
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(lr, data); err != nil {
+			return nil, err
+		}
+		return NewBuffer(data), nil
+	case tipe[0] == TypeCodeCodePoint:
+		return unmarshalCodePointWithLimits(lr, depth)
+	default:
+		return UnmarshalValueWithType(tipe[0], lr)
+	}
+}
+
+func unmarshalCodePointWithLimits(lr *limitedReader, depth int) (Value, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(lr, header); err != nil {
+		return nil, err
+	}
+	immediateCount, opcode := header[0], Opcode(header[1])
+	var op Operation
+	switch immediateCount {
+	case 0:
+		op = BasicOperation{Op: opcode}
+	case 1:
+		val, err := unmarshalValueWithLimits(lr, depth-1)
+		if err != nil {
+			return nil, err
+		}
+		op = ImmediateOperation{Op: opcode, Val: val}
+	default:
+		return nil, errors.New("immediate count must be 0 or 1")
+	}
+	var nextHash common.Hash
+	if _, err := io.ReadFull(lr, nextHash[:]); err != nil {
+		return nil, err
+	}
+	return CodePointValue{Op: op, NextHash: nextHash}, nil
+}
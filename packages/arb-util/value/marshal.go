@@ -0,0 +1,121 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// WireFormatVersion identifies the canonical value encoding written by
+// MarshalValue. It is the encoding produced by marshal_value in the C++ AVM
+// and read by UnmarshalValue, and the one used by the OneStepProof
+// contracts. Each value starts with its type code:
+//
+//	int:             type code, 32 byte big endian value
+//	codepoint:       type code, immediate count (0 or 1), opcode,
+//	                 immediate value if present, 32 byte next hash
+//	hash preimage:   type code, 32 byte hash, 32 byte big endian size
+//	tuple:           type code (TypeCodeTuple plus the tuple length),
+//	                 each element in order
+//	buffer:          type code, 8 byte big endian length, contents
+//	codepoint stub:  type code, 8 byte big endian segment, 8 byte big endian
+//	                 pc, 32 byte hash
+//
+// Any change to this encoding must use a new version. Version 1 encoded
+// codepoint stubs without their segment, which doesn't match the C++ AVM.
+const WireFormatVersion uint8 = 2
+
+// MarshalValue writes the canonical encoding of v to w
+func MarshalValue(v Value, w io.Writer) error {
+	if _, err := w.Write([]byte{v.TypeCode()}); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case IntValue:
+		return v.Marshal(w)
+	case CodePointValue:
+		if op, ok := v.Op.(ImmediateOperation); ok {
+			if _, err := w.Write([]byte{1, byte(op.Op)}); err != nil {
+				return err
+			}
+			if err := MarshalValue(op.Val, w); err != nil {
+				return err
+			}
+		} else if _, err := w.Write([]byte{0, byte(v.Op.GetOp())}); err != nil {
+			return err
+		}
+		_, err := w.Write(v.NextHash[:])
+		return err
+	case HashPreImage:
+		if _, err := w.Write(v.hashImage[:]); err != nil {
+			return err
+		}
+		return NewInt64Value(v.size).Marshal(w)
+	case *TupleValue:
+		for _, child := range v.Contents() {
+			if err := MarshalValue(child, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Buffer:
+		if err := binary.Write(w, binary.BigEndian, uint64(len(v.data))); err != nil {
+			return err
+		}
+		_, err := w.Write(v.data)
+		return err
+	case CodePointStub:
+		return v.Marshal(w)
+	default:
+		return errors.Errorf("can't marshal value of type %T", v)
+	}
+}
+
+// MarshalValueToBytes returns the canonical encoding of v
+func MarshalValueToBytes(v Value) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := MarshalValue(v, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalVersionedValue writes WireFormatVersion followed by the canonical
+// encoding of v
+func MarshalVersionedValue(v Value, w io.Writer) error {
+	if _, err := w.Write([]byte{WireFormatVersion}); err != nil {
+		return err
+	}
+	return MarshalValue(v, w)
+}
+
+// UnmarshalVersionedValue reads a value written by MarshalVersionedValue,
+// rejecting encodings with an unknown version
+func UnmarshalVersionedValue(r io.Reader) (Value, error) {
+	version := make([]byte, 1)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return nil, err
+	}
+	if version[0] != WireFormatVersion {
+		return nil, errors.Errorf("unsupported value wire format version %v", version[0])
+	}
+	return UnmarshalValue(r)
+}
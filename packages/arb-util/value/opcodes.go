@@ -0,0 +1,243 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import "fmt"
+
+var opcodeNames = map[Opcode]string{
+	0x01: "add",
+	0x02: "mul",
+	0x03: "sub",
+	0x04: "div",
+	0x05: "sdiv",
+	0x06: "mod",
+	0x07: "smod",
+	0x08: "addmod",
+	0x09: "mulmod",
+	0x0a: "exp",
+	0x0b: "signextend",
+
+	0x10: "lt",
+	0x11: "gt",
+	0x12: "slt",
+	0x13: "sgt",
+	0x14: "eq",
+	0x15: "iszero",
+	0x16: "and",
+	0x17: "or",
+	0x18: "xor",
+	0x19: "not",
+	0x1a: "byte",
+	0x1b: "shl",
+	0x1c: "shr",
+	0x1d: "sar",
+
+	0x20: "hash",
+	0x21: "type",
+	0x22: "ethhash2",
+	0x23: "keccakf",
+	0x24: "sha256f",
+
+	0x30: "pop",
+	0x31: "spush",
+	0x32: "rpush",
+	0x33: "rset",
+	0x34: "jump",
+	0x35: "cjump",
+	0x36: "stackempty",
+	0x37: "pcpush",
+	0x38: "auxpush",
+	0x39: "auxpop",
+	0x3a: "auxstackempty",
+	0x3b: "nop",
+	0x3c: "errpush",
+	0x3d: "errset",
+
+	0x40: "dup0",
+	0x41: "dup1",
+	0x42: "dup2",
+	0x43: "swap1",
+	0x44: "swap2",
+
+	0x50: "tget",
+	0x51: "tset",
+	0x52: "tlen",
+	0x53: "xget",
+	0x54: "xset",
+
+	0x60: "breakpoint",
+	0x61: "log",
+
+	0x70: "send",
+	0x72: "inbox",
+	0x73: "error",
+	0x74: "halt",
+	0x75: "setgas",
+	0x76: "pushgas",
+	0x77: "errcodepoint",
+	0x78: "pushinsn",
+	0x79: "pushinsnimm",
+	0x7b: "sideload",
+
+	0x80: "ecrecover",
+	0x81: "ecadd",
+	0x82: "ecmul",
+	0x83: "ecpairing",
+
+	0x90: "debugprint",
+
+	0xa0: "newbuffer",
+	0xa1: "getbuffer8",
+	0xa2: "getbuffer64",
+	0xa3: "getbuffer256",
+	0xa4: "setbuffer8",
+	0xa5: "setbuffer64",
+	0xa6: "setbuffer256",
+}
+
+// Name returns the assembly mnemonic of the opcode, matching the names used
+// by the C++ AVM
+func (o Opcode) Name() string {
+	if name, ok := opcodeNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(0x%x)", uint8(o))
+}
+
+// IsValid returns whether the opcode is defined by the AVM
+func (o Opcode) IsValid() bool {
+	_, ok := opcodeNames[o]
+	return ok
+}
+
+// StackEffect is the number of values an operation pops from and then pushes
+// onto the data and aux stacks. A value in an immediate is pushed before the
+// operation runs and isn't counted.
+type StackEffect struct {
+	Pops      int
+	Pushes    int
+	AuxPops   int
+	AuxPushes int
+}
+
+// opcodeStackEffects matches InstructionStackPops and InstructionAuxStackPops
+// in the C++ AVM along with what each operation pushes back
+var opcodeStackEffects = map[Opcode]StackEffect{
+	0x01: {Pops: 2, Pushes: 1},
+	0x02: {Pops: 2, Pushes: 1},
+	0x03: {Pops: 2, Pushes: 1},
+	0x04: {Pops: 2, Pushes: 1},
+	0x05: {Pops: 2, Pushes: 1},
+	0x06: {Pops: 2, Pushes: 1},
+	0x07: {Pops: 2, Pushes: 1},
+	0x08: {Pops: 3, Pushes: 1},
+	0x09: {Pops: 3, Pushes: 1},
+	0x0a: {Pops: 2, Pushes: 1},
+	0x0b: {Pops: 2, Pushes: 1},
+
+	0x10: {Pops: 2, Pushes: 1},
+	0x11: {Pops: 2, Pushes: 1},
+	0x12: {Pops: 2, Pushes: 1},
+	0x13: {Pops: 2, Pushes: 1},
+	0x14: {Pops: 2, Pushes: 1},
+	0x15: {Pops: 1, Pushes: 1},
+	0x16: {Pops: 2, Pushes: 1},
+	0x17: {Pops: 2, Pushes: 1},
+	0x18: {Pops: 2, Pushes: 1},
+	0x19: {Pops: 1, Pushes: 1},
+	0x1a: {Pops: 2, Pushes: 1},
+	0x1b: {Pops: 2, Pushes: 1},
+	0x1c: {Pops: 2, Pushes: 1},
+	0x1d: {Pops: 2, Pushes: 1},
+
+	0x20: {Pops: 1, Pushes: 1},
+	0x21: {Pops: 1, Pushes: 1},
+	0x22: {Pops: 2, Pushes: 1},
+	0x23: {Pops: 1, Pushes: 1},
+	0x24: {Pops: 3, Pushes: 1},
+
+	0x30: {Pops: 1},
+	0x31: {Pushes: 1},
+	0x32: {Pushes: 1},
+	0x33: {Pops: 1},
+	0x34: {Pops: 1},
+	0x35: {Pops: 2},
+	0x36: {Pushes: 1},
+	0x37: {Pushes: 1},
+	0x38: {Pops: 1, AuxPushes: 1},
+	0x39: {Pushes: 1, AuxPops: 1},
+	0x3a: {Pushes: 1},
+	0x3b: {},
+	0x3c: {Pushes: 1},
+	0x3d: {Pops: 1},
+
+	0x40: {Pops: 1, Pushes: 2},
+	0x41: {Pops: 2, Pushes: 3},
+	0x42: {Pops: 3, Pushes: 4},
+	0x43: {Pops: 2, Pushes: 2},
+	0x44: {Pops: 3, Pushes: 3},
+
+	0x50: {Pops: 2, Pushes: 1},
+	0x51: {Pops: 3, Pushes: 1},
+	0x52: {Pops: 1, Pushes: 1},
+	0x53: {Pops: 1, Pushes: 1, AuxPops: 1, AuxPushes: 1},
+	0x54: {Pops: 2, AuxPops: 1, AuxPushes: 1},
+
+	0x60: {},
+	0x61: {Pops: 1},
+
+	0x70: {Pops: 2},
+	0x72: {Pushes: 1},
+	0x73: {},
+	0x74: {},
+	0x75: {Pops: 1},
+	0x76: {Pushes: 1},
+	0x77: {Pushes: 1},
+	0x78: {Pops: 2, Pushes: 1},
+	0x79: {Pops: 3, Pushes: 1},
+	0x7b: {Pops: 1, Pushes: 1},
+
+	0x80: {Pops: 4, Pushes: 1},
+	0x81: {Pops: 4, Pushes: 2},
+	0x82: {Pops: 3, Pushes: 2},
+	0x83: {Pops: 1, Pushes: 1},
+
+	0x90: {Pops: 1},
+
+	0xa0: {Pushes: 1},
+	0xa1: {Pops: 2, Pushes: 1},
+	0xa2: {Pops: 2, Pushes: 1},
+	0xa3: {Pops: 2, Pushes: 1},
+	0xa4: {Pops: 3, Pushes: 1},
+	0xa5: {Pops: 3, Pushes: 1},
+	0xa6: {Pops: 3, Pushes: 1},
+}
+
+// StackEffect returns the stack effect of the opcode. Opcodes the AVM doesn't
+// define have no effect.
+func (o Opcode) StackEffect() StackEffect {
+	return opcodeStackEffects[o]
+}
+
+// Disassemble renders an operation as its mnemonic followed by its immediate,
+// if any, formatted with Format using the given limits
+func Disassemble(op Operation, maxDepth int, maxWidth int) string {
+	if immOp, ok := op.(ImmediateOperation); ok {
+		return fmt.Sprintf("%v %v", immOp.Op.Name(), Format(immOp.Val, maxDepth, maxWidth))
+	}
+	return op.GetOp().Name()
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// ValueStats describes the shape of a value
+type ValueStats struct {
+	// Number of values in the tree, counting every occurrence of a repeated
+	// subvalue and the immediates of codepoints
+	NodeCount uint64
+	// Number of levels of nesting, where a value with no children has depth 1
+	MaxDepth uint64
+	// Length of the value's encoding as read by UnmarshalValue
+	SerializedSize uint64
+	// Number of structurally distinct subvalues
+	DistinctCount uint64
+}
+
+// Stats computes statistics about v
+func Stats(v Value) ValueStats {
+	s := &statsCollector{distinct: make(map[[32]byte]struct{})}
+	_, depth := s.visit(v)
+	s.stats.MaxDepth = depth
+	s.stats.DistinctCount = uint64(len(s.distinct))
+	return s.stats
+}
+
+type statsCollector struct {
+	stats    ValueStats
+	distinct map[[32]byte]struct{}
+}
+
+// visit returns a key identifying the structure of v along with its depth
+func (s *statsCollector) visit(v Value) ([32]byte, uint64) {
+	s.stats.NodeCount++
+	h := sha256.New()
+	h.Write([]byte{v.TypeCode()})
+	depth := uint64(1)
+	switch v := v.(type) {
+	case *TupleValue:
+		s.stats.SerializedSize++
+		for _, child := range v.Contents() {
+			key, childDepth := s.visit(child)
+			h.Write(key[:])
+			if childDepth+1 > depth {
+				depth = childDepth + 1
+			}
+		}
+	case CodePointValue:
+		s.stats.SerializedSize += 1 + 2 + 32
+		h.Write([]byte{byte(v.Op.GetOp())})
+		if op, ok := v.Op.(ImmediateOperation); ok {
+			key, childDepth := s.visit(op.Val)
+			h.Write(key[:])
+			depth = childDepth + 1
+		}
+		h.Write(v.NextHash[:])
+	case IntValue:
+		s.stats.SerializedSize += 1 + 32
+		data := v.ToBytes()
+		h.Write(data[:])
+	case *Buffer:
+		s.stats.SerializedSize += 1 + 8 + uint64(len(v.Data()))
+		h.Write(v.Data())
+	case HashPreImage:
+		s.stats.SerializedSize += 1 + 32 + 32
+		h.Write(v.hashImage[:])
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(v.size))
+		h.Write(size[:])
+	case CodePointStub:
+		s.stats.SerializedSize += 1 + 8 + 8 + 32
+		var location [16]byte
+		binary.BigEndian.PutUint64(location[:8], v.Segment)
+		binary.BigEndian.PutUint64(location[8:], v.PC)
+		h.Write(location[:])
+		h.Write(v.hash[:])
+	default:
+		h.Write([]byte(v.String()))
+	}
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	s.distinct[key] = struct{}{}
+	return key, depth
+}
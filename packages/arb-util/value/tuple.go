@@ -102,6 +102,9 @@ func (tv *TupleValue) Equal(val Value) bool {
 	if !ok {
 		return false
 	}
+	if tup == tv {
+		return true
+	}
 	if tup.Len() != tv.Len() {
 		return false
 	}
@@ -0,0 +1,354 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package value
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func testValues() []Value {
+	inner := NewTuple2(NewInt64Value(3), NewBuffer([]byte{1, 2, 3}))
+	codePoint := CodePointValue{
+		Op:       ImmediateOperation{Op: 0x34, Val: inner},
+		NextHash: common.Hash{1},
+	}
+	return []Value{
+		NewInt64Value(0),
+		NewIntValue(new(big.Int).Lsh(big.NewInt(1), 255)),
+		NewEmptyTuple(),
+		NewTuple2(inner, NewEmptyTuple()),
+		NewBuffer(nil),
+		codePoint,
+		CodePointValue{Op: BasicOperation{Op: 0x01}, NextHash: common.Hash{2}},
+		NewPreImage(common.Hash{3}, 5),
+		CodePointStub{Segment: 2, PC: 7, hash: common.Hash{4}},
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, val := range testValues() {
+		data, err := MarshalValueJSON(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := UnmarshalValueJSON(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(val) {
+			t.Errorf("decoded %v from %s but expected %v", decoded, data, val)
+		}
+	}
+}
+
+func TestJSONValueRoundTrip(t *testing.T) {
+	type wrapper struct {
+		Buffer    JSONValue `json:"buffer"`
+		CodePoint JSONValue `json:"codePoint"`
+	}
+	codePoint := CodePointValue{
+		Op:       ImmediateOperation{Op: 0x34, Val: NewBuffer([]byte{4, 5})},
+		NextHash: common.Hash{1},
+	}
+	original := wrapper{
+		Buffer:    JSONValue{NewBuffer([]byte{1, 2, 3})},
+		CodePoint: JSONValue{codePoint},
+	}
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded wrapper
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Buffer.Equal(original.Buffer.Value) || !decoded.CodePoint.Equal(codePoint) {
+		t.Errorf("decoded %v and %v from %s", decoded.Buffer, decoded.CodePoint, data)
+	}
+}
+
+func TestJSONEncodingIsOptIn(t *testing.T) {
+	// Values used directly keep the default encoding
+	data, err := json.Marshal(struct{ Val Value }{NewInt64Value(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Val":{}}` {
+		t.Errorf("unexpected encoding %s", data)
+	}
+}
+
+func TestUnmarshalValueWithLimits(t *testing.T) {
+	// Tuple(Tuple(Tuple(0)))
+	nested := append([]byte{TypeCodeTuple + 1, TypeCodeTuple + 1, TypeCodeTuple + 1, TypeCodeInt}, make([]byte, 32)...)
+	if _, err := UnmarshalValueWithLimits(bytes.NewReader(nested), 4, 100); err != nil {
+		t.Fatal(err)
+	}
+	_, err := UnmarshalValueWithLimits(bytes.NewReader(nested), 3, 100)
+	if _, ok := err.(LimitExceededError); !ok {
+		t.Errorf("expected depth limit error but got %v", err)
+	}
+	_, err = UnmarshalValueWithLimits(bytes.NewReader(nested), 4, 35)
+	if _, ok := err.(LimitExceededError); !ok {
+		t.Errorf("expected size limit error but got %v", err)
+	}
+	// A value exactly as long as the limit is allowed
+	if _, err := UnmarshalValueWithLimits(bytes.NewReader(nested), 4, int64(len(nested))); err != nil {
+		t.Error(err)
+	}
+
+	// A buffer claiming a huge length must fail before allocating
+	hugeBuffer := []byte{TypeCodeBuffer, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	_, err = UnmarshalValueWithLimits(bytes.NewReader(hugeBuffer), 4, 1000)
+	if _, ok := err.(LimitExceededError); !ok {
+		t.Errorf("expected size limit error but got %v", err)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	lr := &limitedReader{r: bytes.NewReader([]byte("abcdef")), remaining: 3, maxSize: 3}
+	buf := make([]byte, 5)
+	n, err := lr.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "abc" {
+		t.Errorf("expected read to stop at the limit but got %q", buf[:n])
+	}
+	if _, err := lr.Read(buf); err == nil {
+		t.Error("expected read past the limit to fail")
+	} else if _, ok := err.(LimitExceededError); !ok {
+		t.Errorf("expected size limit error but got %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, val := range testValues() {
+		if formatted := Format(val, 0, 0); formatted != val.String() {
+			t.Errorf("unlimited format %v doesn't match %v", formatted, val.String())
+		}
+	}
+
+	var val Value = NewInt64Value(1)
+	for i := 0; i < 10; i++ {
+		val = NewTuple2(val, NewBuffer(nil))
+	}
+	formatted := Format(val, 2, 0)
+	if formatted != "Tuple(Tuple(Tuple<2>(...), Buffer(0x)), Buffer(0x))" {
+		t.Errorf("unexpected depth limited format %v", formatted)
+	}
+	formatted = Format(NewTuple2(val, NewBuffer(make([]byte, 1000))), 0, 50)
+	if len(formatted) != 53 || formatted[50:] != "..." {
+		t.Errorf("unexpected width limited format %v", formatted)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := NewTuple2(NewTuple2(NewInt64Value(1), NewInt64Value(2)), NewInt64Value(3))
+	b := NewTuple2(NewTuple2(NewInt64Value(1), NewInt64Value(4)), NewEmptyTuple())
+	if diffs := Diff(a, a); diffs != nil {
+		t.Errorf("expected no differences but got %v", diffs)
+	}
+	diffs := Diff(a, b)
+	if len(diffs) != 2 {
+		t.Fatalf("expected 2 differences but got %v", diffs)
+	}
+	if diffs[0].String() != "[0.1]: 2 != 4" {
+		t.Errorf("unexpected first difference %v", diffs[0])
+	}
+	if diffs[1].String() != "[1]: 3 != Tuple()" {
+		t.Errorf("unexpected second difference %v", diffs[1])
+	}
+}
+
+func TestStats(t *testing.T) {
+	leaf := NewTuple2(NewInt64Value(1), NewInt64Value(2))
+	val := NewTuple2(leaf, NewTuple2(leaf, NewBuffer([]byte{1, 2})))
+	stats := Stats(val)
+	expected := ValueStats{
+		NodeCount:      9,
+		MaxDepth:       4,
+		SerializedSize: 4 + 4*33 + 11,
+		DistinctCount:  6,
+	}
+	if stats != expected {
+		t.Errorf("expected %+v but got %+v", expected, stats)
+	}
+}
+
+func TestStatsCodePointStub(t *testing.T) {
+	// Stubs at different codepoints are distinct even with the same hash
+	val := NewTuple2(
+		CodePointStub{Segment: 1, PC: 5, hash: common.Hash{1}},
+		CodePointStub{Segment: 1, PC: 6, hash: common.Hash{1}},
+	)
+	stats := Stats(val)
+	expected := ValueStats{
+		NodeCount:      3,
+		MaxDepth:       2,
+		SerializedSize: 1 + 2*(1+8+8+32),
+		DistinctCount:  3,
+	}
+	if stats != expected {
+		t.Errorf("expected %+v but got %+v", expected, stats)
+	}
+	data, err := MarshalValueToBytes(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(data)) != stats.SerializedSize {
+		t.Errorf("serialized size %v doesn't match encoding length %v", stats.SerializedSize, len(data))
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	op := ImmediateOperation{Op: 0x34, Val: NewTuple2(NewInt64Value(1), NewEmptyTuple())}
+	if str := Disassemble(op, 0, 0); str != "jump Tuple(1, Tuple())" {
+		t.Errorf("unexpected disassembly %v", str)
+	}
+	if str := Disassemble(BasicOperation{Op: 0xff}, 0, 0); str != "unknown(0xff)" {
+		t.Errorf("unexpected disassembly %v", str)
+	}
+}
+
+func TestMarshalConformance(t *testing.T) {
+	data, err := ioutil.ReadFile("test_cases.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var testCases []struct {
+		Value string `json:"value"`
+		Name  string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &testCases); err != nil {
+		t.Fatal(err)
+	}
+	for _, testCase := range testCases {
+		encoded, err := hex.DecodeString(testCase.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, err := UnmarshalValue(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.Name, err)
+		}
+		reencoded, err := MarshalValueToBytes(val)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.Name, err)
+		}
+		if !bytes.Equal(encoded, reencoded) {
+			t.Errorf("%v: encoded as %x but expected %x", testCase.Name, reencoded, encoded)
+		}
+	}
+}
+
+func TestVersionedRoundTrip(t *testing.T) {
+	for _, val := range testValues() {
+		var buf bytes.Buffer
+		if err := MarshalVersionedValue(val, &buf); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := UnmarshalVersionedValue(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(val) {
+			t.Errorf("decoded %v but expected %v", decoded, val)
+		}
+	}
+
+	if _, err := UnmarshalVersionedValue(bytes.NewReader([]byte{WireFormatVersion + 1, TypeCodeTuple})); err == nil {
+		t.Error("expected unknown version to be rejected")
+	}
+	if _, err := UnmarshalVersionedValue(bytes.NewReader([]byte{1, TypeCodeTuple})); err == nil {
+		t.Error("expected version 1 to be rejected")
+	}
+}
+
+func TestInternTable(t *testing.T) {
+	table := NewInternTable()
+	a := table.Intern(NewTuple2(NewTuple2(NewInt64Value(1), NewInt64Value(2)), NewInt64Value(3)))
+	b := table.Intern(NewTuple2(NewTuple2(NewInt64Value(1), NewInt64Value(2)), NewInt64Value(3)))
+	if a != b {
+		t.Error("identical values weren't interned to the same instance")
+	}
+	c := table.Intern(NewTuple2(NewInt64Value(1), NewInt64Value(2)))
+	aInner, _ := a.(*TupleValue).GetByInt64(0)
+	if aInner != c {
+		t.Error("subvalue wasn't shared")
+	}
+	if table.Len() != 5 {
+		t.Errorf("expected 5 distinct values but got %v", table.Len())
+	}
+	for _, val := range testValues() {
+		if interned := table.Intern(val); !interned.Equal(val) {
+			t.Errorf("interned %v as %v", val, interned)
+		}
+	}
+}
+
+func TestSmallIntValuesShared(t *testing.T) {
+	if NewInt64Value(42).val != NewInt64Value(42).val {
+		t.Error("small int values should share storage")
+	}
+	val := NewInt64Value(7)
+	val.BigInt().SetInt64(8)
+	if NewInt64Value(7).BigInt().Int64() != 7 {
+		t.Error("shared small int value was modified")
+	}
+}
+
+func TestDecodedSmallIntValuesShared(t *testing.T) {
+	for _, x := range []int64{0, 42, 255, 256} {
+		data, err := MarshalValueToBytes(NewInt64Value(x))
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, err := UnmarshalValue(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := val.(IntValue)
+		if decoded.BigInt().Int64() != x {
+			t.Errorf("decoded %v as %v", x, decoded)
+		}
+		shared := decoded.val == NewInt64Value(x).val
+		if shared != (x < 256) {
+			t.Errorf("decoded %v shared storage: %v", x, shared)
+		}
+	}
+}
+
+func TestOpcodeStackEffects(t *testing.T) {
+	for opcode := range opcodeNames {
+		if _, ok := opcodeStackEffects[opcode]; !ok {
+			t.Error("missing stack effect for", opcode.Name())
+		}
+	}
+	for opcode := range opcodeStackEffects {
+		if !opcode.IsValid() {
+			t.Errorf("stack effect for undefined opcode 0x%x", uint8(opcode))
+		}
+	}
+}